@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"news/store"
+)
+
+// Admin verbosity levels, from quietest to noisiest.
+const (
+	VerbosityErrors = "errors"
+	VerbosityPosts  = "posts"
+	VerbosityAll    = "all"
+)
+
+// Outcome counters tracked per source for the admin digest.
+const (
+	outcomeRun           = "runs"
+	outcomePosted        = "posts"
+	outcomeSkipped       = "skipped"
+	outcomeQueued        = "queued"
+	outcomeNoItems       = "no_items"
+	outcomeFiltered      = "filtered"
+	outcomeNoSignificant = "no_significant"
+	outcomeError         = "errors"
+)
+
+// sourceStatus is the persisted admin view of a single source.
+type sourceStatus struct {
+	// ActiveErrors maps an operation to the last error reported for it, until it recovers.
+	ActiveErrors map[string]string `json:"active_errors,omitempty"`
+	Counts       map[string]int    `json:"counts,omitempty"`
+}
+
+// adminState is the persisted state of the admin notifier.
+type adminState struct {
+	Sources     map[string]*sourceStatus `json:"sources"`
+	DigestSince time.Time                `json:"digest_since"`
+}
+
+// AdminNotifier sends operational notifications to the admin chat according to the configured verbosity.
+type AdminNotifier struct {
+	telegramService *TelegramService
+	chatID          string
+	verbosity       string
+	digestInterval  time.Duration
+	store           *store.JSONFile[adminState]
+}
+
+// NewAdminNotifier creates a new AdminNotifier persisting its state in the configured state directory.
+func NewAdminNotifier(telegramService *TelegramService, config *Config) *AdminNotifier {
+	return &AdminNotifier{
+		telegramService: telegramService,
+		chatID:          config.TelegramChatID,
+		verbosity:       config.AdminVerbosity,
+		digestInterval:  config.AdminDigestInterval,
+		store:           store.NewJSONFile[adminState](filepath.Join(config.StateDir, "admin.json")),
+	}
+}
+
+// Run records that a source was processed.
+func (n *AdminNotifier) Run(sourceName string) {
+	n.record(sourceName, outcomeRun, nil)
+}
+
+// Error reports a failed operation. Repeated identical errors are sent only once,
+// until the operation recovers or the error changes.
+func (n *AdminNotifier) Error(sourceName, operation string, err error) {
+	message := err.Error()
+	changed := false
+	n.record(sourceName, outcomeError, func(status *sourceStatus) {
+		if status.ActiveErrors == nil {
+			status.ActiveErrors = make(map[string]string)
+		}
+		changed = status.ActiveErrors[operation] != message
+		status.ActiveErrors[operation] = message
+	})
+
+	if !changed {
+		LogInfo("Suppressing repeated admin error", "source", sourceName, "operation", operation)
+		return
+	}
+	n.send(sourceName, fmt.Sprintf("Error %s from %s: %v", operation, sourceName, err))
+}
+
+// Resolve reports that an operation succeeded, announcing the recovery if it was failing.
+func (n *AdminNotifier) Resolve(sourceName, operation string) {
+	var previous string
+	n.record(sourceName, "", func(status *sourceStatus) {
+		previous = status.ActiveErrors[operation]
+		delete(status.ActiveErrors, operation)
+	})
+
+	if previous != "" {
+		n.send(sourceName, fmt.Sprintf("Recovered: %s from %s is working again (last error: %s)", operation, sourceName, previous))
+	}
+}
+
+// Posted reports a successfully published post.
+func (n *AdminNotifier) Posted(sourceName, message string) {
+	n.record(sourceName, outcomePosted, nil)
+	if n.verbosity != VerbosityErrors {
+		n.send(sourceName, message)
+	}
+}
+
+// Queued reports a post held back until its channel's schedule allows it.
+func (n *AdminNotifier) Queued(sourceName, message string) {
+	n.record(sourceName, outcomeQueued, nil)
+	if n.verbosity != VerbosityErrors {
+		n.send(sourceName, message)
+	}
+}
+
+// Skipped reports a candidate post that was deliberately not published.
+func (n *AdminNotifier) Skipped(sourceName, message string) {
+	n.record(sourceName, outcomeSkipped, nil)
+	if n.verbosity != VerbosityErrors {
+		n.send(sourceName, message)
+	}
+}
+
+// NoItems reports that a source had no new items.
+func (n *AdminNotifier) NoItems(sourceName string) {
+	n.record(sourceName, outcomeNoItems, nil)
+	if n.verbosity == VerbosityAll {
+		n.send(sourceName, fmt.Sprintf("No new items from %s.", sourceName))
+	}
+}
+
+// Filtered records the number of fetched items the source's filter dropped before analysis.
+func (n *AdminNotifier) Filtered(sourceName string, count int) {
+	n.record(sourceName, "", func(status *sourceStatus) {
+		if status.Counts == nil {
+			status.Counts = make(map[string]int)
+		}
+		status.Counts[outcomeFiltered] += count
+	})
+}
+
+// SourceDisabled reports that a source was disabled after failing too many times in a row.
+func (n *AdminNotifier) SourceDisabled(sourceName string, failures int, err error) {
+	n.send(sourceName, fmt.Sprintf("Disabled %s after %d consecutive failures (last error: %v). Re-enable it with: nonoise sources enable %s",
+		sourceName, failures, err, sourceName))
+}
+
+// NoSignificantNews reports that the analysis found nothing worth posting.
+func (n *AdminNotifier) NoSignificantNews(sourceName string) {
+	n.record(sourceName, outcomeNoSignificant, nil)
+	if n.verbosity == VerbosityAll {
+		n.send(sourceName, fmt.Sprintf("No significant news to report from %s.", sourceName))
+	}
+}
+
+// SendDigestIfDue sends a summary of all runs since the previous digest once the digest interval has elapsed.
+func (n *AdminNotifier) SendDigestIfDue() error {
+	if n.digestInterval <= 0 {
+		return nil
+	}
+
+	var digest string
+	err := n.store.Update(func(state *adminState) error {
+		if state.DigestSince.IsZero() {
+			state.DigestSince = time.Now()
+			return nil
+		}
+		if time.Since(state.DigestSince) < n.digestInterval {
+			return nil
+		}
+
+		digest = renderAdminDigest(state)
+		state.DigestSince = time.Now()
+		for _, status := range state.Sources {
+			status.Counts = nil
+		}
+		return nil
+	})
+	if err != nil || digest == "" {
+		return err
+	}
+	_, err = n.telegramService.SendMessage(n.chatID, digest)
+	return err
+}
+
+// record updates the persisted status of a source, incrementing the given outcome counter if set.
+func (n *AdminNotifier) record(sourceName, outcome string, fn func(*sourceStatus)) {
+	err := n.store.Update(func(state *adminState) error {
+		if state.Sources == nil {
+			state.Sources = make(map[string]*sourceStatus)
+		}
+		status, ok := state.Sources[sourceName]
+		if !ok {
+			status = &sourceStatus{}
+			state.Sources[sourceName] = status
+		}
+		if outcome != "" {
+			if status.Counts == nil {
+				status.Counts = make(map[string]int)
+			}
+			status.Counts[outcome]++
+		}
+		if fn != nil {
+			fn(status)
+		}
+		return nil
+	})
+	if err != nil {
+		LogError("Failed to update admin state", err, "source", sourceName)
+	}
+}
+
+// send delivers a message to the admin chat.
+func (n *AdminNotifier) send(sourceName, message string) {
+	if _, err := n.telegramService.SendMessage(n.chatID, message); err != nil {
+		LogError("Failed to notify admin", err, "source", sourceName)
+	}
+}
+
+// renderAdminDigest formats the per-source counters collected since the last digest.
+func renderAdminDigest(state *adminState) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 <b>Digest since %s</b>\n", state.DigestSince.Format("2006-01-02 15:04 MST"))
+
+	names := make([]string, 0, len(state.Sources))
+	for name := range state.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		status := state.Sources[name]
+		fmt.Fprintf(&b, "\n<b>%s</b>: %d runs, %d posts, %d queued, %d skipped, %d without new items, %d items filtered, %d without significant news, %d errors",
+			html.EscapeString(name),
+			status.Counts[outcomeRun],
+			status.Counts[outcomePosted],
+			status.Counts[outcomeQueued],
+			status.Counts[outcomeSkipped],
+			status.Counts[outcomeNoItems],
+			status.Counts[outcomeFiltered],
+			status.Counts[outcomeNoSignificant],
+			status.Counts[outcomeError],
+		)
+
+		operations := make([]string, 0, len(status.ActiveErrors))
+		for operation := range status.ActiveErrors {
+			operations = append(operations, operation)
+		}
+		sort.Strings(operations)
+		for _, operation := range operations {
+			fmt.Fprintf(&b, "\n  ⚠️ still failing %s: %s", html.EscapeString(operation), html.EscapeString(status.ActiveErrors[operation]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"news/fetcher"
+	"news/store"
+)
+
+// ArchivedPost is a post published to a channel.
+type ArchivedPost struct {
+	ChannelID        string             `json:"channel_id"`
+	MessageID        int                `json:"message_id"`
+	SourceName       string             `json:"source_name"`
+	Links            []string           `json:"links,omitempty"`
+	Text             string             `json:"text"`
+	Sources          []fetcher.NewsItem `json:"sources,omitempty"`
+	Score            int                `json:"score,omitempty"`
+	Tags             []string           `json:"tags,omitempty"`
+	PhotoURL         string             `json:"photo_url,omitempty"`
+	IsPhoto          bool               `json:"is_photo"`
+	ReplyToMessageID int                `json:"reply_to_message_id,omitempty"`
+	PostedAt         time.Time          `json:"posted_at"`
+	EditedAt         time.Time          `json:"edited_at,omitzero"`
+}
+
+// post reconstructs the post as it was handed to the publisher, without the items it was selected from.
+func (p ArchivedPost) post() Post {
+	return Post{
+		SourceName:       p.SourceName,
+		ChannelID:        p.ChannelID,
+		Text:             p.Text,
+		PhotoURL:         p.PhotoURL,
+		Sources:          p.Sources,
+		Score:            p.Score,
+		Tags:             p.Tags,
+		ReplyToMessageID: p.ReplyToMessageID,
+	}
+}
+
+// archiveState is the persisted post archive.
+type archiveState struct {
+	Posts []*ArchivedPost `json:"posts"`
+}
+
+// PostArchive keeps track of published posts so they can be edited, deleted or followed up later.
+type PostArchive struct {
+	store           *store.JSONFile[archiveState]
+	telegramService *TelegramService
+	renderer        *PostRenderer
+	retention       time.Duration
+}
+
+// NewPostArchive creates a new PostArchive persisting its state in the configured state directory.
+func NewPostArchive(telegramService *TelegramService, renderer *PostRenderer, config *Config) *PostArchive {
+	return &PostArchive{
+		store:           store.NewJSONFile[archiveState](filepath.Join(config.StateDir, "posts.json")),
+		telegramService: telegramService,
+		renderer:        renderer,
+		retention:       config.ArchiveRetention,
+	}
+}
+
+// Record stores a post that was published as msg, dropping posts older than the retention period.
+func (a *PostArchive) Record(post Post, msg *Message) error {
+	archived := &ArchivedPost{
+		ChannelID:        post.ChannelID,
+		MessageID:        msg.MessageID,
+		SourceName:       post.SourceName,
+		Links:            post.Links(),
+		Text:             post.Text,
+		Sources:          post.Sources,
+		Score:            post.Score,
+		Tags:             post.Tags,
+		PhotoURL:         post.PhotoURL,
+		IsPhoto:          len(msg.Photo) > 0,
+		ReplyToMessageID: post.ReplyToMessageID,
+		PostedAt:         time.Now(),
+	}
+
+	return a.store.Update(func(state *archiveState) error {
+		cutoff := time.Now().Add(-a.retention)
+		kept := state.Posts[:0]
+		for _, p := range state.Posts {
+			if p.PostedAt.After(cutoff) {
+				kept = append(kept, p)
+			}
+		}
+		state.Posts = append(kept, archived)
+		return nil
+	})
+}
+
+// List returns the archived posts of a channel, or of all channels if channelID is empty, oldest first.
+func (a *PostArchive) List(channelID string) ([]ArchivedPost, error) {
+	return a.Recent(channelID, time.Time{})
+}
+
+// Recent returns the archived posts of a channel published after since, oldest first.
+// An empty channelID matches all channels.
+func (a *PostArchive) Recent(channelID string, since time.Time) ([]ArchivedPost, error) {
+	state, err := a.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []ArchivedPost
+	for _, p := range state.Posts {
+		if (channelID == "" || p.ChannelID == channelID) && p.PostedAt.After(since) {
+			posts = append(posts, *p)
+		}
+	}
+	return posts, nil
+}
+
+// Find returns the archived post with the given channel and message ID.
+func (a *PostArchive) Find(channelID string, messageID int) (*ArchivedPost, error) {
+	state, err := a.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range state.Posts {
+		if p.ChannelID == channelID && p.MessageID == messageID {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("post %d in %s not found in archive", messageID, channelID)
+}
+
+// Edit replaces the text of a published post, rendering it with the channel's post template.
+func (a *PostArchive) Edit(channelID string, messageID int, text string) error {
+	archived, err := a.Find(channelID, messageID)
+	if err != nil {
+		return err
+	}
+
+	post := archived.post()
+	post.Text = text
+	rendered, err := a.renderer.Render(post)
+	if err != nil {
+		return err
+	}
+	if err := a.telegramService.EditPost(channelID, messageID, rendered, archived.IsPhoto, a.renderer.ReplyMarkup(post)); err != nil {
+		return fmt.Errorf("failed to edit post: %w", err)
+	}
+
+	LogInfo("Post edited", "channel_id", channelID, "message_id", messageID)
+	return a.store.Update(func(state *archiveState) error {
+		for _, p := range state.Posts {
+			if p.ChannelID == channelID && p.MessageID == messageID {
+				p.Text = text
+				p.EditedAt = time.Now()
+			}
+		}
+		return nil
+	})
+}
+
+// Delete removes a published post from the channel and the archive.
+func (a *PostArchive) Delete(channelID string, messageID int) error {
+	if _, err := a.Find(channelID, messageID); err != nil {
+		return err
+	}
+	if err := a.telegramService.DeleteMessage(channelID, messageID); err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	LogInfo("Post deleted", "channel_id", channelID, "message_id", messageID)
+	return a.store.Update(func(state *archiveState) error {
+		kept := state.Posts[:0]
+		for _, p := range state.Posts {
+			if p.ChannelID != channelID || p.MessageID != messageID {
+				kept = append(kept, p)
+			}
+		}
+		state.Posts = kept
+		return nil
+	})
+}
+
+// PostUpdate publishes an update as a reply to an earlier post and archives it.
+func (a *PostArchive) PostUpdate(channelID string, messageID int, text string) (*Message, error) {
+	original, err := a.Find(channelID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := Post{
+		SourceName:       original.SourceName,
+		ChannelID:        channelID,
+		Text:             text,
+		Sources:          original.Sources,
+		ReplyToMessageID: messageID,
+	}
+	rendered, err := a.renderer.Render(update)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := a.telegramService.SendMessageWithOptions(channelID, rendered, SendOptions{
+		ReplyToMessageID: messageID,
+		ReplyMarkup:      a.renderer.ReplyMarkup(update),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	LogInfo("Update posted", "channel_id", channelID, "reply_to", messageID, "message_id", msg.MessageID)
+	return msg, a.Record(update, msg)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"news/fetcher"
+	"news/store"
+)
+
+// runCommand executes a command-line subcommand instead of the regular fetch run.
+func runCommand(args []string, reviewService *ReviewService, publisher *Publisher, archive *PostArchive, health *HealthTracker, config *Config) error {
+	switch args[0] {
+	case "review":
+		return runReviewLoop(reviewService, publisher)
+	case "posts":
+		return runPostsCommand(args[1:], archive)
+	case "sources":
+		return runSourcesCommand(args[1:], health, config)
+	default:
+		return fmt.Errorf("unknown command %q (available: review, posts, sources)", args[0])
+	}
+}
+
+// runReviewLoop continuously applies editor decisions and delivers queued posts until the process is interrupted.
+func runReviewLoop(reviewService *ReviewService, publisher *Publisher) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	LogInfo("Waiting for editor decisions, press Ctrl+C to stop")
+	for ctx.Err() == nil {
+		if err := publisher.PublishDue(); err != nil {
+			LogError("Failed to publish queued posts", err)
+		}
+		if err := reviewService.ExpirePending(); err != nil {
+			LogError("Failed to expire pending posts", err)
+		}
+		if err := reviewService.ProcessUpdates(UpdatesPollTimeout); err != nil {
+			LogError("Failed to process review updates", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(DefaultRetryDelay):
+			}
+		}
+	}
+	return nil
+}
+
+const postsUsage = `usage:
+  posts list [channel]
+  posts edit <channel> <message_id> <text | ->
+  posts delete <channel> <message_id>
+  posts update <channel> <message_id> <text | ->
+
+Text "-" is read from standard input.`
+
+// runPostsCommand lists, edits, deletes or follows up on archived posts.
+func runPostsCommand(args []string, archive *PostArchive) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", postsUsage)
+	}
+
+	if args[0] == "list" {
+		channelID := ""
+		if len(args) > 1 {
+			channelID = args[1]
+		}
+		posts, err := archive.List(channelID)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			headline, _, _ := strings.Cut(post.Text, "\n")
+			fmt.Printf("%s\t%d\t%s\t%s\t%s\n", post.ChannelID, post.MessageID, post.PostedAt.Format(time.RFC3339), post.SourceName, headline)
+		}
+		return nil
+	}
+
+	if len(args) < 3 {
+		return fmt.Errorf("%s", postsUsage)
+	}
+	channelID := args[1]
+	messageID, err := strconv.Atoi(args[2])
+	if err != nil {
+		return fmt.Errorf("invalid message ID %q: %w", args[2], err)
+	}
+
+	switch args[0] {
+	case "delete":
+		return archive.Delete(channelID, messageID)
+	case "edit", "update":
+		text, err := commandText(args[3:])
+		if err != nil {
+			return err
+		}
+		if args[0] == "edit" {
+			return archive.Edit(channelID, messageID, text)
+		}
+		_, err = archive.PostUpdate(channelID, messageID, text)
+		return err
+	default:
+		return fmt.Errorf("%s", postsUsage)
+	}
+}
+
+const sourcesUsage = `usage:
+  sources import <file.opml> [category=channel ...]
+  sources export [file.opml]
+  sources discover <site_url>
+  sources status
+  sources enable <name>
+  sources disable <name>
+
+Imported feeds are added to SOURCES_FILE. Feeds in a category are published to the channel
+the category is mapped to, or to the category itself if its name is a channel ID.`
+
+// sourcesSetupCommands are the sources subcommands that need only the sources, so they work
+// before the rest of the configuration is in place.
+var sourcesSetupCommands = []string{"import", "export", "discover"}
+
+// isSourcesSetupCommand reports whether the command-line arguments run one of sourcesSetupCommands.
+func isSourcesSetupCommand(args []string) bool {
+	return len(args) > 1 && args[0] == "sources" && slices.Contains(sourcesSetupCommands, args[1])
+}
+
+// runSourcesCommand manages sources: their health, OPML import and export and feed discovery.
+func runSourcesCommand(args []string, health *HealthTracker, config *Config) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", sourcesUsage)
+	}
+
+	switch args[0] {
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", sourcesUsage)
+		}
+		return importSources(args[1], args[2:], config)
+	case "export":
+		out := os.Stdout
+		if len(args) > 1 {
+			file, err := os.Create(args[1])
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", args[1], err)
+			}
+			defer file.Close()
+			out = file
+		}
+		skipped, err := exportOPML(out, config.Sources, config.TargetChannels)
+		if err != nil {
+			return err
+		}
+		for _, name := range skipped {
+			fmt.Fprintf(os.Stderr, "Skipped %s: not a feed\n", name)
+		}
+		return nil
+	case "status":
+		return printSourceHealth(health, config)
+	case "enable", "disable":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", sourcesUsage)
+		}
+		name := strings.Join(args[1:], " ")
+		if _, ok := config.Sources[name]; !ok {
+			return fmt.Errorf("unknown source %q", name)
+		}
+		return health.SetDisabled(name, args[0] == "disable")
+	case "discover":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", sourcesUsage)
+		}
+		return discoverSources(args[1])
+	default:
+		return fmt.Errorf("%s", sourcesUsage)
+	}
+}
+
+// printSourceHealth prints the health of every configured source.
+func printSourceHealth(health *HealthTracker, config *Config) error {
+	states, err := health.All()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(config.Sources))
+	for name := range config.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04")
+	}
+	for _, name := range names {
+		state := states[name]
+		if state == nil {
+			fmt.Printf("%s\tnot fetched yet\n", name)
+			continue
+		}
+		status := "ok"
+		switch {
+		case state.Disabled:
+			status = "disabled"
+		case state.ConsecutiveFailures > 0:
+			status = fmt.Sprintf("failing (%d), next attempt %s", state.ConsecutiveFailures, formatTime(state.NextAttempt))
+		}
+		fmt.Printf("%s\t%s\tlast success %s\tlast item %s\t%.1f items/day",
+			name, status, formatTime(state.LastSuccess), formatTime(state.LastItemAt), state.ItemsPerDay())
+		if state.LastError != "" {
+			fmt.Printf("\tlast error: %s", state.LastError)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// discoverSources prints the feeds found on a website with their item counts and date parseability.
+func discoverSources(siteURL string) error {
+	if !strings.Contains(siteURL, "://") {
+		siteURL = "https://" + siteURL
+	}
+	candidates, err := fetcher.DiscoverFeeds(siteURL, fetcher.DefaultMaxBodySize, nil)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("no feeds found on %s", siteURL)
+	}
+
+	for _, candidate := range candidates {
+		if candidate.Err != nil {
+			fmt.Printf("%s\tnot usable: %v\n", candidate.URL, candidate.Err)
+			continue
+		}
+		newest := "-"
+		if !candidate.Newest.IsZero() {
+			newest = candidate.Newest.Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\t%d items, %d dated\tnewest %s\t%s\n",
+			candidate.URL, candidate.Format, candidate.Items, candidate.DatedItems, newest, candidate.Title)
+	}
+	return nil
+}
+
+// importSources adds the feeds of an OPML file to the sources file, skipping feeds that are
+// already configured and renaming feeds whose names are taken.
+func importSources(path string, mappings []string, config *Config) error {
+	if config.SourcesFile == "" {
+		return fmt.Errorf("SOURCES_FILE must be set to import sources")
+	}
+	categoryChannels := make(map[string]string)
+	for _, mapping := range mappings {
+		category, channelID, found := strings.Cut(mapping, "=")
+		if !found || strings.TrimSpace(category) == "" || strings.TrimSpace(channelID) == "" {
+			return fmt.Errorf("invalid category mapping %q, expected category=channel", mapping)
+		}
+		categoryChannels[strings.TrimSpace(category)] = strings.TrimSpace(channelID)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	imported, err := importOPML(file, categoryChannels)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	urls := make(map[string]bool)
+	for name, source := range config.Sources {
+		names[name] = true
+		urls[source.URL] = true
+	}
+
+	added := 0
+	err = store.NewJSONFile[[]*SourceConfig](config.SourcesFile).Update(func(sources *[]*SourceConfig) error {
+		for _, source := range *sources {
+			names[source.Name] = true
+			urls[source.URL] = true
+		}
+		for _, source := range imported {
+			if urls[source.URL] {
+				fmt.Printf("Skipped %s: %s is already configured\n", source.Name, source.URL)
+				continue
+			}
+			name := source.Name
+			for i := 2; names[name]; i++ {
+				name = fmt.Sprintf("%s %d", source.Name, i)
+			}
+			source.Name = name
+			names[name] = true
+			urls[source.URL] = true
+			*sources = append(*sources, source)
+			added++
+
+			channels := strings.Join(source.Channels, ";")
+			if channels == "" {
+				channels = "no channel, add one to TARGET_CHANNELS"
+			}
+			fmt.Printf("Added %s\t%s\t%s\n", name, source.URL, channels)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d of %d feeds into %s\n", added, len(imported), config.SourcesFile)
+	return nil
+}
+
+// commandText joins the remaining arguments into a message text, reading standard input for "-".
+func commandText(args []string) (string, error) {
+	if len(args) == 1 && args[0] == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read text from stdin: %w", err)
+		}
+		args = []string{string(data)}
+	}
+
+	text := strings.TrimSpace(strings.Join(args, " "))
+	if text == "" {
+		return "", fmt.Errorf("message text is empty")
+	}
+	return text, nil
+}
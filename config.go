@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,13 +20,47 @@ type Config struct {
 	TelegramAPIKey      string
 	TelegramChatID      string
 	GeminiPrompt        string
-	NewsSources         map[string]string
-	TargetChannels      map[string]string
+	GeminiPromptFile    string
+	PromptFormat        string
+	PromptLanguage      string
+	SourcePrompts       map[string]string
+	ChannelLanguages    map[string]string
+	Sources             map[string]*SourceConfig
+	SourcesFile         string
+	TargetChannels      map[string][]string
+	SourceDisableAfter  int
+	SourceBackoffMax    time.Duration
+	SourceStaleAfter    time.Duration
+	Lookback            time.Duration
+	WatermarkOverlap    time.Duration
+	HostRequestInterval time.Duration
+	HostMaxConcurrency  int
+	MaxCrawlDelay       time.Duration
+	RespectRobots       bool
+	RobotsCacheTTL      time.Duration
+	RobotsAgent         string
 	ContentPreviewLimit int
 	MaxMessageLength    int
 	APITimeout          int
 	RetryAttempts       int
 	RetryDelay          time.Duration
+	StateDir            string
+	ReviewChannels      map[string]bool
+	ReviewExpiry        time.Duration
+	ReviewLoop          bool
+	AdminVerbosity      string
+	AdminDigestInterval time.Duration
+	ArchiveRetention    time.Duration
+	FollowUpWindow      time.Duration
+	FollowUpPolicies    map[string]string
+	ChannelSchedules    map[string]ChannelSchedule
+	DigestChannels      map[string]string
+	DigestSize          int
+	DigestTime          int
+	DigestWeekday       time.Weekday
+	PostTemplate        string
+	PostTemplates       map[string]string
+	ReadMoreButton      string
 }
 
 // LoadConfig loads the configuration from a .env file.
@@ -37,7 +74,24 @@ func LoadConfig() (*Config, error) {
 	geminiAPIKey := getEnv("GEMINI_API_KEY", true)
 	telegramAPIKey := getEnv("TELEGRAM_API_KEY", true)
 	telegramChatID := getEnv("TELEGRAM_CHAT_ID", true)
-	geminiPrompt := getEnv("GEMINI_PROMPT", true)
+
+	// Load the analysis prompt, given inline or as a file, and per-source prompt files
+	geminiPrompt := getEnv("GEMINI_PROMPT", false)
+	geminiPromptFile := getEnv("GEMINI_PROMPT_FILE", false)
+	if geminiPrompt == "" && geminiPromptFile == "" {
+		log.Fatal("GEMINI_PROMPT is not set in .env file")
+	}
+	promptFormat := getEnv("PROMPT_FORMAT", false)
+	switch promptFormat {
+	case "":
+		promptFormat = PromptFormatLegacy
+	case PromptFormatLegacy, PromptFormatTemplate:
+	default:
+		log.Fatalf("Invalid PROMPT_FORMAT %q, expected %q or %q", promptFormat, PromptFormatLegacy, PromptFormatTemplate)
+	}
+	promptLanguage := getEnv("PROMPT_LANGUAGE", false)
+	sourcePrompts := parseKeyValues(getEnv("SOURCE_PROMPTS", false))
+	channelLanguages := parseKeyValues(getEnv("CHANNEL_LANGUAGES", false))
 
 	// Load optional settings with defaults
 	contentPreviewLimit := getEnvAsInt("CONTENT_PREVIEW_LIMIT", ContentPreviewLimit)
@@ -45,30 +99,183 @@ func LoadConfig() (*Config, error) {
 	apiTimeout := getEnvAsInt("API_TIMEOUT", int(DefaultHTTPTimeout/time.Second))
 	retryAttempts := getEnvAsInt("RETRY_ATTEMPTS", DefaultRetryAttempts)
 	retryDelay := getEnvAsInt("RETRY_DELAY", int(DefaultRetryDelay/time.Second))
+	stateDir := getEnv("STATE_DIR", false)
+	if stateDir == "" {
+		stateDir = DefaultStateDir
+	}
 
-	// Load news sources from environment variable
-	newsSourcesEnv := getEnv("NEWS_SOURCES", true)
-	newsSources := parseNewsSources(newsSourcesEnv)
+	// Load editorial review settings
+	reviewChannels := parseChannelSet(getEnv("REVIEW_CHANNELS", false))
+	reviewExpiry := getEnvAsInt("REVIEW_EXPIRY", int(DefaultReviewExpiry/time.Hour))
+	reviewLoop := getEnvAsBool("REVIEW_LOOP", false)
 
-	// Load target channels from environment variable
-	targetChannelsEnv := getEnv("TARGET_CHANNELS", true)
-	targetChannels := parseTargetChannels(targetChannelsEnv)
+	// Load admin notification settings
+	adminVerbosity := parseAdminVerbosity(getEnv("ADMIN_VERBOSITY", false))
+	adminDigestInterval := getEnvAsInt("ADMIN_DIGEST_INTERVAL", 0)
+
+	// Load post archive settings
+	archiveRetention := getEnvAsInt("ARCHIVE_RETENTION", DefaultArchiveRetentionDays)
+
+	// Load follow-up detection settings
+	followUpWindow := getEnvAsInt("FOLLOWUP_WINDOW", int(DefaultFollowUpWindow/time.Hour))
+	followUpPolicies := parseFollowUpPolicies(getEnv("FOLLOWUP_POLICY", false))
+
+	// Load per-channel delivery schedules
+	channelSchedules := parseChannelSchedules(
+		getEnv("CHANNEL_TIMEZONES", false),
+		getEnv("QUIET_HOURS", false),
+		getEnv("QUIET_MODE", false),
+		getEnv("PUBLISH_AT", false),
+	)
+
+	// Load digest settings
+	digestChannels := parseDigestChannels(getEnv("DIGEST_CHANNELS", false))
+	digestSize := getEnvAsInt("DIGEST_SIZE", DefaultDigestSize)
+	digestTime := parseDigestTime(getEnv("DIGEST_TIME", false))
+	digestWeekday := parseWeekday(getEnv("DIGEST_WEEKDAY", false))
+
+	// Load post rendering settings
+	postTemplate := getEnv("POST_TEMPLATE", false)
+	postTemplates := parseKeyValues(getEnv("POST_TEMPLATES", false))
+	readMoreButton := getEnv("READ_MORE_BUTTON", false)
+
+	// Load news sources from environment variable and the optional sources file
+	sourcesFile := getEnv("SOURCES_FILE", false)
+	newsSourcesEnv := getEnv("NEWS_SOURCES", sourcesFile == "")
+	sources, err := loadSources(newsSourcesEnv, sourcesFile, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		log.Fatal("No valid news sources found in NEWS_SOURCES or SOURCES_FILE")
+	}
+
+	// Load source health settings
+	sourceDisableAfter := getEnvAsInt("SOURCE_DISABLE_AFTER", DefaultSourceDisableAfter)
+	sourceBackoffMax := getEnvAsInt("SOURCE_BACKOFF_MAX", int(DefaultSourceBackoffMax/time.Hour))
+	sourceStaleAfter := getEnvAsInt("SOURCE_STALE_AFTER", int(DefaultSourceStaleAfter/time.Hour))
+	lookback := getEnvAsInt("LOOKBACK_HOURS", int(DefaultLookback/time.Hour))
+	watermarkOverlap := getEnvAsInt("WATERMARK_OVERLAP", int(DefaultWatermarkOverlap/time.Hour))
+
+	// Load politeness settings
+	hostRequestInterval := getEnvAsInt("HOST_REQUEST_INTERVAL", int(DefaultHostRequestInterval/time.Second))
+	hostMaxConcurrency := getEnvAsInt("HOST_MAX_CONCURRENCY", DefaultHostMaxConcurrency)
+	maxCrawlDelay := getEnvAsInt("MAX_CRAWL_DELAY", int(DefaultMaxCrawlDelay/time.Second))
+	respectRobots := getEnvAsBool("RESPECT_ROBOTS", true)
+	robotsCacheHours := getEnvAsInt("ROBOTS_CACHE_HOURS", DefaultRobotsCacheHours)
+	robotsAgent := getEnv("ROBOTS_AGENT", false)
+
+	// Load target channels from environment variable and the sources file
+	targetChannelsEnv := getEnv("TARGET_CHANNELS", sourcesFile == "")
+	targetChannels := loadTargetChannels(targetChannelsEnv, sources)
+	if len(targetChannels) == 0 {
+		log.Fatal("No valid target channels found in TARGET_CHANNELS or SOURCES_FILE")
+	}
 
 	return &Config{
 		GeminiAPIKey:        geminiAPIKey,
 		TelegramAPIKey:      telegramAPIKey,
 		TelegramChatID:      telegramChatID,
 		GeminiPrompt:        geminiPrompt,
-		NewsSources:         newsSources,
+		GeminiPromptFile:    geminiPromptFile,
+		PromptFormat:        promptFormat,
+		PromptLanguage:      promptLanguage,
+		SourcePrompts:       sourcePrompts,
+		ChannelLanguages:    channelLanguages,
+		Sources:             sources,
+		SourcesFile:         sourcesFile,
 		TargetChannels:      targetChannels,
+		SourceDisableAfter:  sourceDisableAfter,
+		SourceBackoffMax:    time.Duration(sourceBackoffMax) * time.Hour,
+		SourceStaleAfter:    time.Duration(sourceStaleAfter) * time.Hour,
+		Lookback:            time.Duration(lookback) * time.Hour,
+		WatermarkOverlap:    time.Duration(watermarkOverlap) * time.Hour,
+		HostRequestInterval: time.Duration(hostRequestInterval) * time.Second,
+		HostMaxConcurrency:  hostMaxConcurrency,
+		MaxCrawlDelay:       time.Duration(maxCrawlDelay) * time.Second,
+		RespectRobots:       respectRobots,
+		RobotsCacheTTL:      time.Duration(robotsCacheHours) * time.Hour,
+		RobotsAgent:         robotsAgent,
 		ContentPreviewLimit: contentPreviewLimit,
 		MaxMessageLength:    maxMessageLength,
 		APITimeout:          apiTimeout,
 		RetryAttempts:       retryAttempts,
 		RetryDelay:          time.Duration(retryDelay) * time.Second,
+		StateDir:            stateDir,
+		ReviewChannels:      reviewChannels,
+		ReviewExpiry:        time.Duration(reviewExpiry) * time.Hour,
+		ReviewLoop:          reviewLoop,
+		AdminVerbosity:      adminVerbosity,
+		AdminDigestInterval: time.Duration(adminDigestInterval) * time.Hour,
+		ArchiveRetention:    time.Duration(archiveRetention) * 24 * time.Hour,
+		FollowUpWindow:      time.Duration(followUpWindow) * time.Hour,
+		FollowUpPolicies:    followUpPolicies,
+		ChannelSchedules:    channelSchedules,
+		DigestChannels:      digestChannels,
+		DigestSize:          digestSize,
+		DigestTime:          digestTime,
+		DigestWeekday:       digestWeekday,
+		PostTemplate:        postTemplate,
+		PostTemplates:       postTemplates,
+		ReadMoreButton:      readMoreButton,
+	}, nil
+}
+
+// LoadSourcesConfig loads only the sources and their target channels, for the commands setting up
+// sources. Unlike LoadConfig, it needs neither API keys, a prompt nor channels, and the sources
+// file may be missing or empty, as before sources are first imported into it.
+func LoadSourcesConfig() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error loading .env file: %v", err)
+	}
+	sourcesFile := getEnv("SOURCES_FILE", false)
+	sources, err := loadSources(getEnv("NEWS_SOURCES", false), sourcesFile, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Config{
+		Sources:        sources,
+		SourcesFile:    sourcesFile,
+		TargetChannels: loadTargetChannels(getEnv("TARGET_CHANNELS", false), sources),
 	}, nil
 }
 
+// loadSources loads the sources of NEWS_SOURCES and of the sources file, if set. A missing
+// sources file is an error unless it is optional.
+func loadSources(newsSources, sourcesFile string, optionalFile bool) (map[string]*SourceConfig, error) {
+	sources := make(map[string]*SourceConfig)
+	for name, url := range parseNewsSources(newsSources) {
+		sources[name] = &SourceConfig{Name: name, URL: url}
+	}
+	if sourcesFile == "" {
+		return sources, nil
+	}
+	fileSources, err := loadSourcesFile(sourcesFile)
+	if optionalFile && errors.Is(err, fs.ErrNotExist) {
+		return sources, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range fileSources {
+		sources[source.Name] = source
+	}
+	return sources, nil
+}
+
+// loadTargetChannels combines the channels of TARGET_CHANNELS with those listed by the sources.
+func loadTargetChannels(targetChannelsEnv string, sources map[string]*SourceConfig) map[string][]string {
+	targetChannels := parseTargetChannels(targetChannelsEnv)
+	for name, source := range sources {
+		for _, channelID := range source.Channels {
+			if !slices.Contains(targetChannels[name], channelID) {
+				targetChannels[name] = append(targetChannels[name], channelID)
+			}
+		}
+	}
+	return targetChannels
+}
+
 // getEnv retrieves an environment variable and validates it if required.
 func getEnv(key string, required bool) string {
 	value := os.Getenv(key)
@@ -93,6 +300,21 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsBool retrieves an environment variable and converts it to a boolean.
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Printf("Invalid value for %s: %s, using default: %t", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
 // parseNewsSources parses the NEWS_SOURCES environment variable.
 func parseNewsSources(newsSourcesEnv string) map[string]string {
 	sources := make(map[string]string)
@@ -111,34 +333,190 @@ func parseNewsSources(newsSourcesEnv string) map[string]string {
 		}
 	}
 	
-	if len(sources) == 0 {
-		log.Fatal("No valid news sources found in NEWS_SOURCES environment variable")
-	}
-	
 	return sources
 }
 
 // parseTargetChannels parses the TARGET_CHANNELS environment variable.
-func parseTargetChannels(targetChannelsEnv string) map[string]string {
-	channels := make(map[string]string)
+func parseTargetChannels(targetChannelsEnv string) map[string][]string {
+	channels := make(map[string][]string)
 	
-	// Expected format: "SourceName:ChannelID,SourceName2:ChannelID2"
-	pairs := strings.Split(targetChannelsEnv, ",")
+	// Expected format: "SourceName:ChannelID,SourceName2:ChannelID2;ChannelID3"
+	for sourceName, channelIDs := range parseKeyValues(targetChannelsEnv) {
+		for _, channelID := range strings.Split(channelIDs, ";") {
+			channelID = strings.TrimSpace(channelID)
+			if channelID != "" {
+				channels[sourceName] = append(channels[sourceName], channelID)
+			}
+		}
+	}
 	
-	for _, pair := range pairs {
+	return channels
+}
+
+// parseChannelSet parses a comma-separated list of channel IDs, e.g. "@channel1,@channel2".
+func parseChannelSet(channelsEnv string) map[string]bool {
+	channels := make(map[string]bool)
+	for _, channel := range strings.Split(channelsEnv, ",") {
+		channel = strings.TrimSpace(channel)
+		if channel != "" {
+			channels[channel] = true
+		}
+	}
+	return channels
+}
+
+// parseAdminVerbosity validates the ADMIN_VERBOSITY environment variable.
+func parseAdminVerbosity(verbosity string) string {
+	switch verbosity = strings.ToLower(strings.TrimSpace(verbosity)); verbosity {
+	case VerbosityErrors, VerbosityPosts, VerbosityAll:
+		return verbosity
+	case "":
+		return VerbosityAll
+	default:
+		log.Printf("Invalid value for ADMIN_VERBOSITY: %s, using default: %s", verbosity, VerbosityAll)
+		return VerbosityAll
+	}
+}
+
+// parseKeyValues parses a list in the "key1:value1,key2:value2" format, skipping malformed pairs.
+func parseKeyValues(env string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(env, ",") {
 		parts := strings.SplitN(pair, ":", 2)
 		if len(parts) == 2 {
-			sourceName := strings.TrimSpace(parts[0])
-			channelID := strings.TrimSpace(parts[1])
-			if sourceName != "" && channelID != "" {
-				channels[sourceName] = channelID
+			key := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			if key != "" && value != "" {
+				values[key] = value
 			}
 		}
 	}
-	
-	if len(channels) == 0 {
-		log.Fatal("No valid target channels found in TARGET_CHANNELS environment variable")
+	return values
+}
+
+// parseFollowUpPolicies parses the FOLLOWUP_POLICY environment variable.
+func parseFollowUpPolicies(policiesEnv string) map[string]string {
+	// Expected format: "ChannelID:policy,ChannelID2:policy2"
+	policies := parseKeyValues(policiesEnv)
+	for channelID, policy := range policies {
+		switch policy {
+		case FollowUpReply, FollowUpSkip, FollowUpStandalone, FollowUpOff:
+		default:
+			log.Printf("Invalid follow-up policy for %s: %s, using default: %s", channelID, policy, FollowUpReply)
+			policies[channelID] = FollowUpReply
+		}
+	}
+	return policies
+}
+
+// parseChannelSchedules builds per-channel delivery schedules from the CHANNEL_TIMEZONES,
+// QUIET_HOURS, QUIET_MODE and PUBLISH_AT environment variables.
+func parseChannelSchedules(timezonesEnv, quietHoursEnv, quietModeEnv, publishAtEnv string) map[string]ChannelSchedule {
+	schedules := make(map[string]ChannelSchedule)
+	get := func(channelID string) ChannelSchedule {
+		schedule, ok := schedules[channelID]
+		if !ok {
+			schedule = ChannelSchedule{Location: time.Local, QuietMode: QuietModeSilent}
+		}
+		return schedule
+	}
+
+	// Expected format: "ChannelID:Europe/Moscow,ChannelID2:UTC"
+	for channelID, name := range parseKeyValues(timezonesEnv) {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			log.Printf("Invalid time zone for %s: %s, using local time", channelID, name)
+			continue
+		}
+		schedule := get(channelID)
+		schedule.Location = location
+		schedules[channelID] = schedule
+	}
+
+	// Expected format: "ChannelID:23:00-07:00,ChannelID2:22-08"
+	for channelID, window := range parseKeyValues(quietHoursEnv) {
+		startStr, endStr, _ := strings.Cut(window, "-")
+		start, err := parseClock(startStr)
+		if err != nil {
+			log.Printf("Invalid quiet hours for %s: %s", channelID, window)
+			continue
+		}
+		end, err := parseClock(endStr)
+		if err != nil {
+			log.Printf("Invalid quiet hours for %s: %s", channelID, window)
+			continue
+		}
+		schedule := get(channelID)
+		schedule.QuietStart, schedule.QuietEnd = start, end
+		schedules[channelID] = schedule
+	}
+
+	// Expected format: "ChannelID:silent,ChannelID2:defer"
+	for channelID, mode := range parseKeyValues(quietModeEnv) {
+		if mode != QuietModeSilent && mode != QuietModeDefer {
+			log.Printf("Invalid quiet mode for %s: %s, using default: %s", channelID, mode, QuietModeSilent)
+			continue
+		}
+		schedule := get(channelID)
+		schedule.QuietMode = mode
+		schedules[channelID] = schedule
+	}
+
+	// Expected format: "ChannelID:09:00;13:00;19:00,ChannelID2:08:30"
+	for channelID, slotsStr := range parseKeyValues(publishAtEnv) {
+		var slots []int
+		for _, slotStr := range strings.Split(slotsStr, ";") {
+			slot, err := parseClock(slotStr)
+			if err != nil {
+				log.Printf("Invalid publishing time for %s: %s", channelID, slotStr)
+				continue
+			}
+			slots = append(slots, slot)
+		}
+		schedule := get(channelID)
+		schedule.Slots = slots
+		schedules[channelID] = schedule
+	}
+
+	return schedules
+}
+
+// parseDigestChannels parses the DIGEST_CHANNELS environment variable.
+func parseDigestChannels(digestChannelsEnv string) map[string]string {
+	// Expected format: "ChannelID:daily,ChannelID2:weekly"
+	channels := parseKeyValues(digestChannelsEnv)
+	for channelID, period := range channels {
+		if period != DigestDaily && period != DigestWeekly {
+			log.Printf("Invalid digest period for %s: %s, using default: %s", channelID, period, DigestDaily)
+			channels[channelID] = DigestDaily
+		}
 	}
-	
 	return channels
-}
\ No newline at end of file
+}
+
+// parseDigestTime parses the DIGEST_TIME environment variable into minutes since midnight.
+func parseDigestTime(digestTimeEnv string) int {
+	if digestTimeEnv == "" {
+		digestTimeEnv = DefaultDigestTime
+	}
+	minutes, err := parseClock(digestTimeEnv)
+	if err != nil {
+		log.Printf("Invalid value for DIGEST_TIME: %s, using default: %s", digestTimeEnv, DefaultDigestTime)
+		minutes, _ = parseClock(DefaultDigestTime)
+	}
+	return minutes
+}
+
+// parseWeekday parses a day name such as "sunday" or "Sun", defaulting to Sunday.
+func parseWeekday(weekdayEnv string) time.Weekday {
+	if weekdayEnv == "" {
+		return time.Sunday
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(weekdayEnv, day.String()) || strings.EqualFold(weekdayEnv, day.String()[:3]) {
+			return day
+		}
+	}
+	log.Printf("Invalid weekday: %s, using default: %s", weekdayEnv, time.Sunday)
+	return time.Sunday
+}
@@ -23,34 +23,74 @@ const (
 	MaxTelegramCaptionLength = 1024
 	MaxPhotoRetries          = 3
 	PhotoRetryDelay          = 3 * time.Second
-)
+	UpdatesPollTimeout       = 30 // seconds, used for getUpdates long polling
 
-// User agent and headers for HTTP requests
-const (
-	UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36"
-	Accept    = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9"
-	AcceptLang = "en-US,en;q=0.9"
+	// Editorial review
+	DefaultReviewExpiry = 24 * time.Hour
+	DefaultStateDir     = "data"
+
+	// Post archive
+	DefaultArchiveRetentionDays = 30
+	DefaultFollowUpWindow       = 48 * time.Hour
+
+	// Digests
+	DefaultDigestSize = 5
+	DefaultDigestTime = "20:00"
+
+	// Source health
+	DefaultSourceDisableAfter = 10
+	SourceBackoffBase         = 15 * time.Minute
+	DefaultSourceBackoffMax   = 12 * time.Hour
+	DefaultSourceStaleAfter   = 48 * time.Hour
+	DefaultLookback           = 24 * time.Hour
+	DefaultWatermarkOverlap   = 2 * time.Hour
+	FeedRediscoveryInterval   = 24 * time.Hour
+
+	// Politeness towards publishers
+	DefaultHostRequestInterval = 1 * time.Second
+	DefaultHostMaxConcurrency  = 2
+	DefaultMaxCrawlDelay       = 60 * time.Second
+	DefaultRobotsCacheHours    = 24
+
+	// Post rendering
+	DefaultPostTemplate = "{{.Text}}{{with .Link}}\n\n<a href=\"{{escape .}}\">{{escape $.SourceName}}</a>{{end}}{{with .Identifier}}\n\n{{.}}{{end}}"
 )
 
 // Gemini API constants
 const (
 	GeminiModel = "gemini-2.5-pro"
-)
 
-// Russian date parsing constants
-var RussianMonthReplacer = map[string]string{
-	"Янв": "Jan", "Фев": "Feb", "Мар": "Mar", "Апр": "Apr",
-	"Май": "May", "Июн": "Jun", "Июл": "Jul", "Авг": "Aug",
-	"Сен": "Sep", "Окт": "Oct", "Ноя": "Nov", "Дек": "Dec",
-}
+	// StoryClassificationPrompt instructs the model to relate a candidate post to recent posts.
+	StoryClassificationPrompt = `You compare a candidate news post with posts recently published to the same channel.
+Classify the candidate as one of:
+- "duplicate": it reports the same event as a recent post without significant new facts.
+- "follow_up": it reports a significant new development of an event covered by a recent post.
+- "new": it is about an event none of the recent posts covers.
 
-var RussianDayReplacer = map[string]string{
-	"Пн": "Mon", "Вт": "Tue", "Ср": "Wed", "Чт": "Thu",
-	"Пт": "Fri", "Сб": "Sat", "Вс": "Sun",
-}
+Respond with a JSON object: {"kind": "new" | "follow_up" | "duplicate", "related_message_id": <message_id of the related recent post, or 0 for new>, "reason": "<one short sentence>"}`
+
+	// SourceIdentificationPrompt instructs the model to find the articles a post is based on.
+	SourceIdentificationPrompt = `Below are a news post and the list of articles it may have been written from. Identify the articles the post is based on.
+
+Respond with a JSON array of the indices of those articles, most relevant first, e.g. [3] or [0, 5]. Respond with [] if none of them matches.`
+
+	// ItemScoringPrompt instructs the model to rate every item for digests.
+	ItemScoringPrompt = `Rate the long-term global significance of each of the following news items on a scale from 1 to 10, where 10 is an event likely to be remembered worldwide for years and 1 is trivial or purely local news. Ads, horoscopes and entertainment get 1.
+
+Respond with a JSON array containing one object per item: [{"index": <item index>, "score": <1-10>}]`
+
+	// DigestPrompt instructs the model to write a digest post; %s is the digest period.
+	DigestPrompt = `Write a %s news digest for a Telegram channel from the stories below, ordered from most to least significant.
+For each story write a bold headline wrapped in <b> and </b>, one or two short sentences with the key facts, and the source link as <a href="LINK">SOURCE</a>.
+Separate stories with a blank line. Use only the <b>, <i> and <a> HTML tags. Write in the language of the stories. Output only the digest text.`
+
+	// PostRewritePrompt instructs the model to rewrite a post for another language; %s is the language.
+	PostRewritePrompt = `Rewrite the following Telegram news post in %s for readers of that language.
+Keep the facts, the structure and the length. Keep the bold headline wrapped in <b> and </b> and any other HTML tags and links as they are.
+Translate names and terms the way media in that language usually do. Output only the rewritten post.`
+)
 
 // News source configurations
 const (
-	SVTVSourceName  = "SVTV"
-	MeduzaSourceName = "Meduza"
+	DigestSourceName = "Digest"
 )
\ No newline at end of file
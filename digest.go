@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"news/fetcher"
+	"news/store"
+)
+
+// Digest periods configurable per channel.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestEntry is a scored news item collected for a channel's next digest.
+type DigestEntry struct {
+	SourceName  string    `json:"source_name"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Content     string    `json:"content"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	PublishedOn time.Time `json:"published_on"`
+	Score       int       `json:"score"`
+}
+
+// channelDigest is the persisted digest state of a single channel.
+type channelDigest struct {
+	Entries  []DigestEntry `json:"entries"`
+	LastSent time.Time     `json:"last_sent,omitzero"`
+}
+
+// digestState is the persisted state of all digest channels.
+type digestState struct {
+	Channels map[string]*channelDigest `json:"channels"`
+}
+
+// DigestService collects scored items for digest channels and publishes their periodic digests.
+type DigestService struct {
+	store         *store.JSONFile[digestState]
+	geminiService *GeminiService
+	publisher     *Publisher
+	notifier      *AdminNotifier
+	config        *Config
+}
+
+// NewDigestService creates a new DigestService persisting its state in the configured state directory.
+func NewDigestService(geminiService *GeminiService, publisher *Publisher, notifier *AdminNotifier, config *Config) *DigestService {
+	return &DigestService{
+		store:         store.NewJSONFile[digestState](filepath.Join(config.StateDir, "digest.json")),
+		geminiService: geminiService,
+		publisher:     publisher,
+		notifier:      notifier,
+		config:        config,
+	}
+}
+
+// IsDigestChannel reports whether the channel receives digests instead of real-time posts.
+func (s *DigestService) IsDigestChannel(channelID string) bool {
+	_, ok := s.config.DigestChannels[channelID]
+	return ok
+}
+
+// Collect scores the items and stores them for the next digest of each of the given channels.
+func (s *DigestService) Collect(sourceName string, items []fetcher.NewsItem, channelIDs []string) error {
+	scores, err := s.geminiService.ScoreItems(items, s.config.RetryAttempts, s.config.RetryDelay)
+	if err != nil {
+		return fmt.Errorf("failed to score items: %w", err)
+	}
+
+	return s.store.Update(func(state *digestState) error {
+		if state.Channels == nil {
+			state.Channels = make(map[string]*channelDigest)
+		}
+		now := time.Now()
+		for _, channelID := range channelIDs {
+			digest, ok := state.Channels[channelID]
+			if !ok {
+				digest = &channelDigest{}
+				state.Channels[channelID] = digest
+			}
+			if digest.LastSent.IsZero() {
+				// The first digest of a channel waits for its next digest time rather than the next run
+				digest.LastSent = s.lastBoundary(channelID, s.config.DigestChannels[channelID], now)
+			}
+
+			known := make(map[string]bool, len(digest.Entries))
+			for _, entry := range digest.Entries {
+				known[entry.Link] = true
+			}
+			for i, item := range items {
+				if item.Link != "" && known[item.Link] {
+					continue
+				}
+				digest.Entries = append(digest.Entries, DigestEntry{
+					SourceName:  sourceName,
+					Title:       item.Title,
+					Link:        item.Link,
+					Content:     truncateRunes(item.Content, ContentPreviewLimit),
+					ImageURL:    item.ImageURL,
+					PublishedOn: item.PublishedOn,
+					Score:       scores[i],
+				})
+			}
+			LogInfo("Items collected for digest", "channel_id", channelID, "source", sourceName, "items", len(items), "pending_entries", len(digest.Entries))
+		}
+		return nil
+	})
+}
+
+// PublishDue writes and publishes the digests of all channels whose digest time has passed.
+func (s *DigestService) PublishDue() error {
+	state, err := s.store.Load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for channelID, period := range s.config.DigestChannels {
+		digest := state.Channels[channelID]
+		if digest == nil || len(digest.Entries) == 0 {
+			continue
+		}
+		boundary := s.lastBoundary(channelID, period, now)
+		if !digest.LastSent.Before(boundary) {
+			continue
+		}
+
+		if err := s.publish(channelID, period, digest.Entries); err != nil {
+			LogError("Failed to publish digest", err, "channel_id", channelID)
+			s.notifier.Error(DigestSourceName, fmt.Sprintf("writing digest for %s", channelID), err)
+			continue
+		}
+		s.notifier.Resolve(DigestSourceName, fmt.Sprintf("writing digest for %s", channelID))
+
+		err := s.store.Update(func(state *digestState) error {
+			state.Channels[channelID] = &channelDigest{LastSent: now}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish writes a digest of the top entries and hands it to the publisher. It fails if the digest
+// could not be written or sent, so the entries are kept for the next run.
+func (s *DigestService) publish(channelID, period string, entries []DigestEntry) error {
+	top := append([]DigestEntry(nil), entries...)
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Score > top[j].Score })
+	if len(top) > s.config.DigestSize {
+		top = top[:s.config.DigestSize]
+	}
+
+	text, err := s.geminiService.WriteDigest(top, period, s.config.ChannelLanguages[channelID], s.config.RetryAttempts, s.config.RetryDelay)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("gemini returned an empty digest")
+	}
+
+	items := make([]fetcher.NewsItem, len(top))
+	for i, entry := range top {
+		items[i] = fetcher.NewsItem{Title: entry.Title, Link: entry.Link, PublishedOn: entry.PublishedOn, ImageURL: entry.ImageURL}
+	}
+
+	LogInfo("Publishing digest", "channel_id", channelID, "period", period, "stories", len(top))
+	return s.publisher.Publish(Post{
+		SourceName: DigestSourceName,
+		ChannelID:  channelID,
+		Text:       sanitizeAnalysis(text),
+		Items:      items,
+		Sources:    items,
+	})
+}
+
+// lastBoundary returns the most recent digest time at or before now in the channel's time zone.
+func (s *DigestService) lastBoundary(channelID, period string, now time.Time) time.Time {
+	location := time.Local
+	if schedule, ok := s.config.ChannelSchedules[channelID]; ok {
+		location = schedule.Location
+	}
+
+	local := now.In(location)
+	boundary := time.Date(local.Year(), local.Month(), local.Day(), s.config.DigestTime/60, s.config.DigestTime%60, 0, 0, location)
+	if boundary.After(local) {
+		boundary = boundary.AddDate(0, 0, -1)
+	}
+	if period == DigestWeekly {
+		for boundary.Weekday() != s.config.DigestWeekday {
+			boundary = boundary.AddDate(0, 0, -1)
+		}
+	}
+	return boundary
+}
+
+// truncateRunes shortens s to at most limit runes.
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestServiceLastBoundary(t *testing.T) {
+	location := time.FixedZone("UTC+3", 3*60*60)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, location)
+	}
+	s := &DigestService{config: &Config{
+		DigestTime:       20 * 60,
+		DigestWeekday:    time.Sunday,
+		ChannelSchedules: map[string]ChannelSchedule{"@local": {Location: location}},
+	}}
+
+	tests := []struct {
+		name   string
+		period string
+		now    time.Time
+		want   time.Time
+	}{
+		{"daily after the digest time", DigestDaily, at(17, 21, 0), at(17, 20, 0)},
+		{"daily at the digest time", DigestDaily, at(17, 20, 0), at(17, 20, 0)},
+		{"daily before the digest time", DigestDaily, at(17, 19, 59), at(16, 20, 0)},
+		{"daily across a month", DigestDaily, at(1, 8, 0), time.Date(2026, 9, 30, 20, 0, 0, 0, location)},
+		{"daily in the channel's time zone", DigestDaily, time.Date(2026, 10, 17, 18, 30, 0, 0, time.UTC), at(17, 20, 0)},
+		{"weekly on the digest day", DigestWeekly, at(18, 20, 30), at(18, 20, 0)},
+		{"weekly before the time on the digest day", DigestWeekly, at(18, 9, 0), at(11, 20, 0)},
+		{"weekly during the week", DigestWeekly, at(16, 12, 0), at(11, 20, 0)},
+		{"weekly the day after", DigestWeekly, at(19, 1, 0), at(18, 20, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.lastBoundary("@local", tt.period, tt.now); !got.Equal(tt.want) {
+				t.Errorf("lastBoundary(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Weekday
+	}{
+		{"", time.Sunday},
+		{"Monday", time.Monday},
+		{"friday", time.Friday},
+		{"SAT", time.Saturday},
+		{"someday", time.Sunday},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseWeekday(tt.value); got != tt.want {
+				t.Errorf("parseWeekday(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"news/utils"
+)
+
+// BlueskyAPI is the public AppView endpoint serving Bluesky data without authentication.
+const BlueskyAPI = "https://public.api.bsky.app"
+
+// blueskyPageSize is the number of posts requested, the most the API returns at once.
+const blueskyPageSize = 100
+
+// blueskyImage is an image of a post's embed view.
+type blueskyImage struct {
+	Fullsize string `json:"fullsize"`
+	Thumb    string `json:"thumb"`
+}
+
+// blueskyEmbed is the subset of a post's embed view the fetcher uses: images, a video, a link
+// card or, for quotes with media, one of the others nested in Media.
+type blueskyEmbed struct {
+	Images   []blueskyImage `json:"images"`
+	Thumb    string         `json:"thumbnail"`
+	External *struct {
+		Thumb string `json:"thumb"`
+	} `json:"external"`
+	Media *blueskyEmbed `json:"media"`
+}
+
+// blueskyFeed is the subset of an app.bsky.feed.getAuthorFeed response the fetcher uses.
+type blueskyFeed struct {
+	Feed []struct {
+		Post struct {
+			URI    string `json:"uri"`
+			Author struct {
+				Handle      string `json:"handle"`
+				DisplayName string `json:"displayName"`
+			} `json:"author"`
+			Record struct {
+				Text      string    `json:"text"`
+				CreatedAt time.Time `json:"createdAt"`
+				Langs     []string  `json:"langs"`
+				Tags      []string  `json:"tags"`
+				Reply     *struct {
+					Parent struct {
+						URI string `json:"uri"`
+					} `json:"parent"`
+				} `json:"reply"`
+			} `json:"record"`
+			Embed *blueskyEmbed `json:"embed"`
+		} `json:"post"`
+		Reason *struct {
+			Type string `json:"$type"`
+		} `json:"reason"`
+	} `json:"feed"`
+}
+
+// BlueskyActor returns the handle or DID of an account given as a handle, with or without "@",
+// a DID or a bsky.app profile link.
+func BlueskyActor(account string) (string, error) {
+	actor := strings.TrimSpace(account)
+	if strings.Contains(actor, "bsky.app/profile/") {
+		_, rest, _ := strings.Cut(actor, "bsky.app/profile/")
+		actor, _, _ = strings.Cut(rest, "/")
+	}
+	actor = strings.TrimPrefix(actor, "@")
+	if actor == "" || strings.ContainsAny(actor, "/?# ") || !strings.Contains(actor, ".") && !strings.HasPrefix(actor, "did:") {
+		return "", fmt.Errorf("invalid Bluesky account %q", account)
+	}
+	return actor, nil
+}
+
+// BlueskyFetcher reads an account's posts from its Bluesky author feed, leaving out replies.
+type BlueskyFetcher struct {
+	// Account is the account's handle, DID or profile link, see BlueskyActor.
+	Account    string
+	SourceName string
+	// API is the AppView endpoint; BlueskyAPI if empty.
+	API         string
+	MaxBodySize int64
+	HTTP        *HTTPOptions
+}
+
+// Fetch fetches the account's posts published after since.
+func (f *BlueskyFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
+	actor, err := BlueskyActor(f.Account)
+	if err != nil {
+		return nil, err
+	}
+	api := f.API
+	if api == "" {
+		api = BlueskyAPI
+	}
+	feedURL := fmt.Sprintf("%s/xrpc/app.bsky.feed.getAuthorFeed?actor=%s&filter=posts_no_replies&limit=%d",
+		strings.TrimSuffix(api, "/"), url.QueryEscape(actor), blueskyPageSize)
+
+	return utils.Retry(attempts, delay, func() ([]NewsItem, error) {
+		client, req, err := createHTTPRequest(feedURL, "application/json", f.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		defer client.CloseIdleConnections()
+		req = skipRobots(req)
+		body, _, err := getDocument(client, req, f.MaxBodySize, "")
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Fetching news", "source", f.SourceName, "account", actor)
+		return ParseBlueskyFeed(bytes.NewReader(body), since, f.SourceName)
+	})
+}
+
+// ParseBlueskyFeed extracts the posts published after since from an app.bsky.feed.getAuthorFeed
+// response. Reposts of other accounts' posts and replies to them are left out; a thread's
+// continuations are kept.
+func ParseBlueskyFeed(r io.Reader, since time.Time, sourceName string) ([]NewsItem, error) {
+	var feed blueskyFeed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("error parsing author feed: %w", err)
+	}
+
+	var newsItems []NewsItem
+	for _, entry := range feed.Feed {
+		post := entry.Post
+		if entry.Reason != nil && entry.Reason.Type == "app.bsky.feed.defs#reasonRepost" || !post.Record.CreatedAt.After(since) {
+			continue
+		}
+		if reply := post.Record.Reply; reply != nil && blueskyRepo(reply.Parent.URI) != blueskyRepo(post.URI) {
+			continue
+		}
+
+		content := strings.TrimSpace(post.Record.Text)
+		var media []Media
+		if post.Embed != nil {
+			media = post.Embed.media()
+		}
+		if content == "" && len(media) == 0 {
+			continue
+		}
+
+		author := post.Author.DisplayName
+		if author == "" {
+			author = "@" + post.Author.Handle
+		}
+		item := NewsItem{
+			GUID:        post.URI,
+			Title:       firstLineTitle(content, author),
+			Link:        fmt.Sprintf("https://bsky.app/profile/%s/post/%s", post.Author.Handle, path.Base(post.URI)),
+			Content:     content,
+			PublishedOn: post.Record.CreatedAt,
+			Media:       media,
+			Categories:  post.Record.Tags,
+			Authors:     []string{author},
+			SourceName:  sourceName,
+			FeedTitle:   author,
+		}
+		if len(post.Record.Langs) > 0 {
+			item.Language = post.Record.Langs[0]
+		}
+		for _, m := range media {
+			if m.Medium == "image" {
+				item.ImageURL = m.URL
+				break
+			}
+		}
+		newsItems = append(newsItems, item)
+	}
+	return newsItems, nil
+}
+
+// blueskyRepo returns the DID of the account an at:// record URI belongs to.
+func blueskyRepo(uri string) string {
+	repo, _, _ := strings.Cut(strings.TrimPrefix(uri, "at://"), "/")
+	return repo
+}
+
+// media returns the images of an embed view, including video thumbnails and link card pictures.
+func (e *blueskyEmbed) media() []Media {
+	var media []Media
+	for _, image := range e.Images {
+		if image.Fullsize != "" {
+			media = append(media, Media{URL: image.Fullsize, Medium: "image"})
+		}
+	}
+	if e.Thumb != "" {
+		media = append(media, Media{URL: e.Thumb, Medium: "image"})
+	}
+	if e.External != nil && e.External.Thumb != "" {
+		media = append(media, Media{URL: e.External.Thumb, Medium: "image"})
+	}
+	if e.Media != nil {
+		media = append(media, e.Media.media()...)
+	}
+	return media
+}
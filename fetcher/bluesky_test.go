@@ -0,0 +1,128 @@
+package fetcher
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// parseBlueskyFixture parses a recorded app.bsky.feed.getAuthorFeed response from testdata.
+func parseBlueskyFixture(t *testing.T, since time.Time) []NewsItem {
+	t.Helper()
+	file, err := os.Open("testdata/bluesky_author_feed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	items, err := ParseBlueskyFeed(file, since, "NoNoise")
+	if err != nil {
+		t.Fatalf("ParseBlueskyFeed: %v", err)
+	}
+	return items
+}
+
+func TestParseBlueskyFeed(t *testing.T) {
+	since := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	items := parseBlueskyFixture(t, since)
+
+	// The pinned post is older than since; the repost and the reply to another account are left out
+	want := []NewsItem{
+		{
+			// A reply continuing the account's own thread is kept
+			GUID:        "at://did:plc:nonoise123/app.bsky.feed.post/3kthread0002",
+			Title:       "Update: the airport reopened at noon.",
+			Link:        "https://bsky.app/profile/nonoise.bsky.social/post/3kthread0002",
+			Content:     "Update: the airport reopened at noon.",
+			PublishedOn: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+			Authors:     []string{"NoNoise"},
+			Language:    "en",
+			SourceName:  "NoNoise",
+			FeedTitle:   "NoNoise",
+		},
+		{
+			// A quote with media has the media's images
+			GUID:        "at://did:plc:nonoise123/app.bsky.feed.post/3kquote00001",
+			Title:       "The photo everyone is sharing, explained",
+			Link:        "https://bsky.app/profile/nonoise.bsky.social/post/3kquote00001",
+			Content:     "The photo everyone is sharing, explained",
+			PublishedOn: time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC),
+			ImageURL:    "https://cdn.bsky.app/img/feed_fullsize/plain/did:plc:nonoise123/bafkcrowd@jpeg",
+			Media:       []Media{{URL: "https://cdn.bsky.app/img/feed_fullsize/plain/did:plc:nonoise123/bafkcrowd@jpeg", Medium: "image"}},
+			Authors:     []string{"NoNoise"},
+			Language:    "en",
+			SourceName:  "NoNoise",
+			FeedTitle:   "NoNoise",
+		},
+		{
+			// A link card's thumbnail is the image
+			GUID:        "at://did:plc:nonoise123/app.bsky.feed.post/3kexternal01",
+			Title:       "Parliament passes the budget #budget",
+			Link:        "https://bsky.app/profile/nonoise.bsky.social/post/3kexternal01",
+			Content:     "Parliament passes the budget #budget",
+			PublishedOn: time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC),
+			ImageURL:    "https://cdn.bsky.app/img/feed_thumbnail/plain/did:plc:nonoise123/bafkbudget@jpeg",
+			Media:       []Media{{URL: "https://cdn.bsky.app/img/feed_thumbnail/plain/did:plc:nonoise123/bafkbudget@jpeg", Medium: "image"}},
+			Categories:  []string{"politics"},
+			Authors:     []string{"NoNoise"},
+			Language:    "en",
+			SourceName:  "NoNoise",
+			FeedTitle:   "NoNoise",
+		},
+		{
+			// A video without text is titled after the author, its thumbnail being the image
+			GUID:        "at://did:plc:nonoise123/app.bsky.feed.post/3kvideo00001",
+			Title:       "NoNoise",
+			Link:        "https://bsky.app/profile/nonoise.bsky.social/post/3kvideo00001",
+			PublishedOn: time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC),
+			ImageURL:    "https://video.bsky.app/watch/did%3Aplc%3Anonoise123/bafkvideo/thumbnail.jpg",
+			Media:       []Media{{URL: "https://video.bsky.app/watch/did%3Aplc%3Anonoise123/bafkvideo/thumbnail.jpg", Medium: "image"}},
+			Authors:     []string{"NoNoise"},
+			Language:    "en",
+			SourceName:  "NoNoise",
+			FeedTitle:   "NoNoise",
+		},
+		{
+			// Images are taken in full size; an author without a display name goes by handle
+			GUID:        "at://did:plc:nonoise123/app.bsky.feed.post/3kimages0001",
+			Title:       "Airport closed by fog",
+			Link:        "https://bsky.app/profile/nonoise.bsky.social/post/3kimages0001",
+			Content:     "Airport closed by fog\nAll flights are delayed.",
+			PublishedOn: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+			ImageURL:    "https://cdn.bsky.app/img/feed_fullsize/plain/did:plc:nonoise123/bafkfog1@jpeg",
+			Media: []Media{
+				{URL: "https://cdn.bsky.app/img/feed_fullsize/plain/did:plc:nonoise123/bafkfog1@jpeg", Medium: "image"},
+				{URL: "https://cdn.bsky.app/img/feed_fullsize/plain/did:plc:nonoise123/bafkfog2@jpeg", Medium: "image"},
+			},
+			Authors:    []string{"@nonoise.bsky.social"},
+			Language:   "en",
+			SourceName: "NoNoise",
+			FeedTitle:  "@nonoise.bsky.social",
+		},
+	}
+	compareItems(t, items, want)
+}
+
+func TestParseBlueskyFeedSince(t *testing.T) {
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []string
+	}{
+		{"pinned post included", time.Time{}, []string{"3kpinned0001", "3kthread0002", "3kquote00001", "3kexternal01", "3kvideo00001", "3kimages0001"}},
+		{"cutoff at a post", time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC), []string{"3kthread0002", "3kquote00001"}},
+		{"after the last post", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts []string
+			for _, item := range parseBlueskyFixture(t, tt.since) {
+				posts = append(posts, path.Base(item.GUID))
+			}
+			if !reflect.DeepEqual(posts, tt.want) {
+				t.Errorf("posts = %q, want %q", posts, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+)
+
+// DefaultMaxBodySize is the largest response body, after decompression, a fetcher reads.
+const DefaultMaxBodySize = 10 << 20
+
+// acceptEncoding lists the content encodings readBody can decode.
+const acceptEncoding = "gzip, deflate, br"
+
+var (
+	xmlDeclarationRe  = regexp.MustCompile(`^\s*<\?xml[^>]*\?>`)
+	xmlEncodingRe     = regexp.MustCompile(`encoding\s*=\s*["']([^"']*)["']`)
+	htmlMetaCharsetRe = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?([\w-]+)`)
+)
+
+// StatusError reports a response with a status other than 200 OK.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to fetch %s, status code: %d", e.URL, e.StatusCode)
+}
+
+// Gone reports whether the document no longer exists at the URL.
+func (e *StatusError) Gone() bool {
+	return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+}
+
+// getDocument performs the request, within the limits of the shared Politeness, and returns the
+// response body transcoded to UTF-8, along with the final URL after redirects for resolving
+// relative links.
+func getDocument(client *http.Client, req *http.Request, maxSize int64, charsetOverride string) ([]byte, *url.URL, error) {
+	release, err := politeness.acquire(client, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching %s: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &StatusError{URL: req.URL.String(), StatusCode: resp.StatusCode}
+	}
+
+	body, err := readBody(resp, maxSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err = toUTF8(body, resp.Header.Get("Content-Type"), charsetOverride)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Request.URL, nil
+}
+
+// readBody reads a response body, decompressing it according to its Content-Encoding and failing
+// if it is larger than maxSize bytes, or DefaultMaxBodySize if maxSize is not positive.
+func readBody(resp *http.Response, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBodySize
+	}
+
+	var reader io.Reader = resp.Body
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress response: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		// Servers disagree on whether deflate means zlib-wrapped or raw data
+		buffered, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if zr, err := zlib.NewReader(bytes.NewReader(buffered)); err == nil {
+			defer zr.Close()
+			reader = zr
+		} else {
+			reader = flate.NewReader(bytes.NewReader(buffered))
+		}
+	case "br":
+		reader = brotli.NewReader(resp.Body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("response is larger than %d bytes", maxSize)
+	}
+	return body, nil
+}
+
+// toUTF8 transcodes a document to UTF-8. The encoding is taken from override if set, otherwise
+// from a byte order mark, the charset of the Content-Type header, the XML declaration or an HTML
+// meta tag, in that order. The XML declaration of a transcoded document is updated to match.
+func toUTF8(body []byte, contentType, override string) ([]byte, error) {
+	label := override
+	if label == "" {
+		label = detectCharset(body, contentType)
+	}
+
+	if label == "" || isUTF8Label(label) {
+		body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	} else {
+		encoding, name := charset.Lookup(label)
+		if encoding == nil {
+			return nil, fmt.Errorf("unknown charset %q", label)
+		}
+		decoded, err := encoding.NewDecoder().Bytes(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+		}
+		body = bytes.TrimPrefix(decoded, []byte("\xef\xbb\xbf"))
+	}
+
+	// The feed parser would otherwise decode the already converted text a second time
+	if declaration := xmlDeclarationRe.Find(body); declaration != nil {
+		fixed := xmlEncodingRe.ReplaceAll(declaration, []byte(`encoding="utf-8"`))
+		body = append(fixed, body[len(declaration):]...)
+	}
+	return body, nil
+}
+
+// isUTF8Label reports whether a charset label names UTF-8.
+func isUTF8Label(label string) bool {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "utf-8", "utf8":
+		return true
+	}
+	return false
+}
+
+// detectCharset returns the charset declared for a document, or an empty string if none is.
+func detectCharset(body []byte, contentType string) string {
+	switch {
+	case bytes.HasPrefix(body, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte("\xfe\xff")):
+		return "utf-16be"
+	case bytes.HasPrefix(body, []byte("\xff\xfe")):
+		return "utf-16le"
+	}
+
+	// Servers often label everything as UTF-8; trust that only if the body actually is UTF-8
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		if !isUTF8Label(params["charset"]) || utf8.Valid(body) {
+			return params["charset"]
+		}
+	}
+
+	if declaration := xmlDeclarationRe.Find(body); declaration != nil {
+		if match := xmlEncodingRe.FindSubmatch(declaration); match != nil {
+			return string(match[1])
+		}
+	}
+
+	// Like browsers, only look for a meta tag near the start of the document, and never in JSON
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return ""
+	}
+	head := body
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if match := htmlMetaCharsetRe.FindSubmatch(head); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
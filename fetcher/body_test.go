@@ -0,0 +1,143 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestDetectCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+	}{
+		{"nothing declared", "<rss></rss>", "", ""},
+		{"utf-8 byte order mark", "\xef\xbb\xbf<rss/>", "text/xml; charset=windows-1251", "utf-8"},
+		{"utf-16 big endian byte order mark", "\xfe\xff\x00<", "", "utf-16be"},
+		{"utf-16 little endian byte order mark", "\xff\xfe<\x00", "", "utf-16le"},
+		{"header", "<rss/>", "application/rss+xml; charset=windows-1251", "windows-1251"},
+		{"header over the declaration", `<?xml version="1.0" encoding="koi8-r"?><rss/>`, "text/xml; charset=windows-1251", "windows-1251"},
+		{"utf-8 header on a valid body", "<p>Привет</p>", "text/html; charset=UTF-8", "UTF-8"},
+		// Servers often claim UTF-8 for documents in another encoding
+		{"utf-8 header on an invalid body", `<?xml version="1.0" encoding="windows-1251"?><p>` + "\xcf\xf0\xe8\xe2\xe5\xf2", "text/xml; charset=utf-8", "windows-1251"},
+		{"xml declaration", `<?xml version="1.0" encoding='ISO-8859-1'?><rss/>`, "text/xml", "ISO-8859-1"},
+		{"html meta charset", `<html><head><meta charset="windows-1251"></head></html>`, "text/html", "windows-1251"},
+		{"html meta http-equiv", `<html><head><meta http-equiv="Content-Type" content="text/html; charset=koi8-r"></head></html>`, "text/html", "koi8-r"},
+		{"meta tag beyond the head", "<html>" + strings.Repeat(" ", 1100) + `<meta charset="koi8-r">`, "text/html", ""},
+		{"meta tag in json", `{"html": "<meta charset=\"koi8-r\">"}`, "application/json", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectCharset([]byte(tt.body), tt.contentType); got != tt.want {
+				t.Errorf("detectCharset() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		override    string
+		want        string
+		wantErr     bool
+	}{
+		{"utf-8", "<p>Привет</p>", "text/html", "", "<p>Привет</p>", false},
+		{"byte order mark removed", "\xef\xbb\xbf<p>ok</p>", "", "", "<p>ok</p>", false},
+		{"windows-1251 from the header", "<p>\xcf\xf0\xe8\xe2\xe5\xf2</p>", "text/html; charset=windows-1251", "", "<p>Привет</p>", false},
+		{"latin-1 from the declaration", `<?xml version="1.0" encoding="ISO-8859-1"?><title>caf` + "\xe9</title>", "", "", `<?xml version="1.0" encoding="utf-8"?><title>café</title>`, false},
+		{"utf-16", "\xff\xfe<\x00p\x00>\x00", "", "", "<p>", false},
+		{"override", "<p>\xcf\xf0\xe8\xe2\xe5\xf2</p>", "text/html; charset=utf-8", "cp1251", "<p>Привет</p>", false},
+		{"unknown charset", "<p>x</p>", "text/html; charset=klingon", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toUTF8([]byte(tt.body), tt.contentType, tt.override)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("toUTF8() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toUTF8(): %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("toUTF8() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	const text = "<rss><channel><title>Compressed</title></channel></rss>"
+	compress := func(newWriter func(io.Writer) io.WriteCloser) string {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		io.WriteString(w, text)
+		w.Close()
+		return buf.String()
+	}
+	gzipped := compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	zlibbed := compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })
+	deflated := compress(func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	})
+	brotlied := compress(func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) })
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     string
+		maxSize  int64
+		want     string
+		wantErr  bool
+	}{
+		{name: "identity", body: text, want: text},
+		{name: "gzip", encoding: "gzip", body: gzipped, want: text},
+		{name: "x-gzip", encoding: "x-gzip", body: gzipped, want: text},
+		{name: "zlib deflate", encoding: "deflate", body: zlibbed, want: text},
+		{name: "raw deflate", encoding: "deflate", body: deflated, want: text},
+		{name: "brotli", encoding: "br", body: brotlied, want: text},
+		{name: "encoding ignores case", encoding: " GZIP ", body: gzipped, want: text},
+		{name: "at the size limit", body: text, maxSize: int64(len(text)), want: text},
+		{name: "over the size limit", body: text, maxSize: int64(len(text)) - 1, wantErr: true},
+		// The limit applies to the decompressed size
+		{name: "decompressed over the size limit", encoding: "gzip", body: gzipped, maxSize: int64(len(text)) - 1, wantErr: true},
+		{name: "corrupt gzip", encoding: "gzip", body: "not gzip", wantErr: true},
+		{name: "unsupported encoding", encoding: "zstd", body: text, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader(tt.body))}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+			got, err := readBody(resp, tt.maxSize)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readBody() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readBody(): %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
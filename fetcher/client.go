@@ -0,0 +1,160 @@
+package fetcher
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Default request settings, chosen to look like a browser so sites do not block the fetchers.
+const (
+	DefaultUserAgent      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36"
+	DefaultTimeout        = 30 * time.Second
+	defaultAccept         = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9"
+	defaultAcceptLanguage = "en-US,en;q=0.9"
+)
+
+// HTTPOptions are the settings of the HTTP requests made for a source. The zero value, like a
+// nil pointer, uses the defaults.
+type HTTPOptions struct {
+	// Proxy is the URL of an HTTP, HTTPS or SOCKS5 proxy, e.g. "socks5://127.0.0.1:1080". The
+	// proxy of the HTTP_PROXY and HTTPS_PROXY environment variables is used if it is empty.
+	Proxy string `json:"proxy,omitempty"`
+	// Headers replace the default headers, including User-Agent, or add to them.
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies map[string]string `json:"cookies,omitempty"`
+	// Username and Password are sent with basic authentication, BearerToken as a bearer token.
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	BearerToken string `json:"bearer_token,omitempty"`
+	// CAFile adds the certificates in a PEM file to the trusted ones, for sites with a private CA.
+	CAFile string `json:"ca_file,omitempty"`
+	// CertFile and KeyFile are a PEM client certificate and key for sites requiring one.
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	// InsecureSkipVerify turns off certificate verification, for sites with broken certificates.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// IgnoreRobots exempts the source from robots.txt, for publishers that allow the bot; its
+	// requests are still rate limited.
+	IgnoreRobots bool `json:"ignore_robots,omitempty"`
+	// TimeoutSeconds limits each request, including reading the body; DefaultTimeout if zero.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// Validate checks that the proxy URL is valid and the certificate files can be loaded.
+func (o *HTTPOptions) Validate() error {
+	_, err := NewHTTPClient(o)
+	return err
+}
+
+// NewHTTPClient creates a client with the proxy, TLS settings and timeout of the options, which
+// may be nil. It is the one place the fetchers get their clients from.
+func NewHTTPClient(options *HTTPOptions) (*http.Client, error) {
+	if options == nil {
+		options = &HTTPOptions{}
+	}
+	client := &http.Client{Timeout: DefaultTimeout}
+	if options.TimeoutSeconds > 0 {
+		client.Timeout = time.Duration(options.TimeoutSeconds) * time.Second
+	}
+	if options.Proxy == "" && options.CAFile == "" && options.CertFile == "" && !options.InsecureSkipVerify {
+		// The default transport pools connections across fetchers
+		return client, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", options.Proxy, err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+	if options.CAFile != "" {
+		pem, err := os.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in CA file %s", options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if options.CertFile != "" || options.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	client.Transport = transport
+	return client, nil
+}
+
+// newRequest creates a GET request with browser-like headers, accept as the Accept header if set,
+// and the headers, cookies and credentials of the options, which may be nil.
+func newRequest(link, accept string, options *HTTPOptions) (*http.Request, error) {
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if accept == "" {
+		accept = defaultAccept
+	}
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Language", defaultAcceptLanguage)
+	// Setting Accept-Encoding ourselves turns off the transport's transparent gzip handling; readBody decodes instead
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if options == nil {
+		return req, nil
+	}
+
+	for name, value := range options.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range options.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	if options.IgnoreRobots {
+		req = skipRobots(req)
+	}
+	switch {
+	case options.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+options.BearerToken)
+	case options.Username != "" || options.Password != "":
+		req.SetBasicAuth(options.Username, options.Password)
+	}
+	return req, nil
+}
+
+// createHTTPRequest creates the client and GET request a fetcher uses with a source's options.
+func createHTTPRequest(link, accept string, options *HTTPOptions) (*http.Client, *http.Request, error) {
+	client, err := NewHTTPClient(options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := newRequest(link, accept, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, req, nil
+}
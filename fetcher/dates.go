@@ -0,0 +1,165 @@
+package fetcher
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policies for items without a parseable publication date.
+const (
+	UndatedSkip      = "skip"       // drop the item
+	UndatedFetchTime = "fetch_time" // treat the item as published when it was fetched
+	UndatedUpdated   = "updated"    // use the item's update time, dropping it if there is none
+)
+
+// defaultDateLayouts are tried after any source-specific layouts.
+var defaultDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC850,
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// dateLocales maps a language code to its month and day names, each translated to the English
+// abbreviation Go layouts use. Longer names are listed with their abbreviations so that declined
+// forms such as "января" are recognized.
+var dateLocales = map[string]map[string][]string{
+	"ru": {
+		"Jan": {"января", "январь", "янв"}, "Feb": {"февраля", "февраль", "фев"},
+		"Mar": {"марта", "март", "мар"}, "Apr": {"апреля", "апрель", "апр"},
+		"May": {"мая", "май"}, "Jun": {"июня", "июнь", "июн"},
+		"Jul": {"июля", "июль", "июл"}, "Aug": {"августа", "август", "авг"},
+		"Sep": {"сентября", "сентябрь", "сент", "сен"}, "Oct": {"октября", "октябрь", "окт"},
+		"Nov": {"ноября", "ноябрь", "ноя"}, "Dec": {"декабря", "декабрь", "дек"},
+		"Mon": {"понедельник", "пн"}, "Tue": {"вторник", "вт"}, "Wed": {"среда", "ср"},
+		"Thu": {"четверг", "чт"}, "Fri": {"пятница", "пт"}, "Sat": {"суббота", "сб"}, "Sun": {"воскресенье", "вс"},
+	},
+	"uk": {
+		"Jan": {"січня", "січень", "січ"}, "Feb": {"лютого", "лютий", "лют"},
+		"Mar": {"березня", "березень", "бер"}, "Apr": {"квітня", "квітень", "квіт"},
+		"May": {"травня", "травень", "трав"}, "Jun": {"червня", "червень", "черв"},
+		"Jul": {"липня", "липень", "лип"}, "Aug": {"серпня", "серпень", "серп"},
+		"Sep": {"вересня", "вересень", "вер"}, "Oct": {"жовтня", "жовтень", "жовт"},
+		"Nov": {"листопада", "листопад", "лист"}, "Dec": {"грудня", "грудень", "груд"},
+		"Mon": {"понеділок", "пн"}, "Tue": {"вівторок", "вт"}, "Wed": {"середа", "ср"},
+		"Thu": {"четвер", "чт"}, "Fri": {"п'ятниця", "пт"}, "Sat": {"субота", "сб"}, "Sun": {"неділя", "нд"},
+	},
+	"de": {
+		"Jan": {"Januar", "Jän"}, "Feb": {"Februar"}, "Mar": {"März", "Mär"}, "Apr": {"April"},
+		"May": {"Mai"}, "Jun": {"Juni"}, "Jul": {"Juli"}, "Aug": {"August"},
+		"Sep": {"September", "Sept"}, "Oct": {"Oktober", "Okt"}, "Nov": {"November"}, "Dec": {"Dezember", "Dez"},
+		"Mon": {"Montag", "Mo"}, "Tue": {"Dienstag", "Di"}, "Wed": {"Mittwoch", "Mi"},
+		"Thu": {"Donnerstag", "Do"}, "Fri": {"Freitag", "Fr"}, "Sat": {"Samstag", "Sa"}, "Sun": {"Sonntag", "So"},
+	},
+	"fr": {
+		"Jan": {"janvier", "janv."}, "Feb": {"février", "févr."}, "Mar": {"mars"}, "Apr": {"avril", "avr."},
+		"May": {"mai"}, "Jun": {"juin"}, "Jul": {"juillet", "juil."}, "Aug": {"août"},
+		"Sep": {"septembre", "sept."}, "Oct": {"octobre", "oct."}, "Nov": {"novembre", "nov."}, "Dec": {"décembre", "déc."},
+		"Mon": {"lundi"}, "Tue": {"mardi"}, "Wed": {"mercredi"}, "Thu": {"jeudi"},
+		"Fri": {"vendredi"}, "Sat": {"samedi"}, "Sun": {"dimanche"},
+	},
+	"es": {
+		"Jan": {"enero", "ene"}, "Feb": {"febrero"}, "Mar": {"marzo"}, "Apr": {"abril", "abr"},
+		"May": {"mayo"}, "Jun": {"junio"}, "Jul": {"julio"}, "Aug": {"agosto", "ago"},
+		"Sep": {"septiembre", "setiembre"}, "Oct": {"octubre"}, "Nov": {"noviembre"}, "Dec": {"diciembre", "dic"},
+		"Mon": {"lunes"}, "Tue": {"martes"}, "Wed": {"miércoles"}, "Thu": {"jueves"},
+		"Fri": {"viernes"}, "Sat": {"sábado"}, "Sun": {"domingo"},
+	},
+}
+
+// DateParser parses publication dates with a chain of layouts, retrying with localized month
+// and day names translated to English.
+type DateParser struct {
+	layouts   []string
+	replacers []*strings.Replacer
+}
+
+// NewDateParser creates a DateParser trying the given layouts before the built-in ones.
+// Locales restrict the languages whose month and day names are recognized; all are by default.
+func NewDateParser(layouts []string, locales []string) (*DateParser, error) {
+	if len(locales) == 0 {
+		for locale := range dateLocales {
+			locales = append(locales, locale)
+		}
+		sort.Strings(locales)
+	}
+
+	parser := &DateParser{layouts: append(append([]string(nil), layouts...), defaultDateLayouts...)}
+	for _, locale := range locales {
+		names, ok := dateLocales[locale]
+		if !ok {
+			return nil, fmt.Errorf("unknown date locale %q", locale)
+		}
+		parser.replacers = append(parser.replacers, newLocaleReplacer(names))
+	}
+	return parser, nil
+}
+
+// newLocaleReplacer builds a replacer translating the localized names in any letter case,
+// matching longer names first.
+func newLocaleReplacer(names map[string][]string) *strings.Replacer {
+	type pair struct{ from, to string }
+	var pairs []pair
+	for english, localized := range names {
+		for _, name := range localized {
+			lower := strings.ToLower(name)
+			first, size := utf8.DecodeRuneInString(lower)
+			title := string(unicode.ToUpper(first)) + lower[size:]
+			for _, variant := range []string{name, lower, title, strings.ToUpper(name)} {
+				pairs = append(pairs, pair{variant, english})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return len(pairs[i].from) > len(pairs[j].from) })
+
+	oldnew := make([]string, 0, len(pairs)*2)
+	for _, p := range pairs {
+		oldnew = append(oldnew, p.from, p.to)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// Parse parses a date, first as is and then with each locale's names translated.
+// Dates without a time zone are taken as local time.
+func (p *DateParser) Parse(value string) (time.Time, error) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	candidates := []string{value}
+	for _, replacer := range p.replacers {
+		if translated := replacer.Replace(value); translated != value {
+			candidates = append(candidates, translated)
+		}
+	}
+	for _, candidate := range candidates {
+		for _, layout := range p.layouts {
+			if t, err := time.ParseInLocation(layout, candidate, time.Local); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date format %q", value)
+}
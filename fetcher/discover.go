@@ -0,0 +1,173 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+// commonFeedPaths are where sites that do not announce their feeds usually have them.
+var commonFeedPaths = []string{
+	"/feed", "/feed/", "/rss", "/rss/", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml",
+	"/feed.json", "/index.rss", "/rss/all", "/feeds/posts/default", "/?feed=rss2",
+}
+
+// feedLinkTypes are the media types of <link rel="alternate"> elements pointing to feeds.
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+	"application/rdf+xml":   true,
+	"application/xml":       true,
+	"text/xml":              true,
+}
+
+// FeedCandidate is a feed found on a website, with what parsing it revealed.
+type FeedCandidate struct {
+	URL    string
+	Title  string
+	Format string // "rss", "atom" or "json"
+	Items  int
+	// DatedItems is the number of items with a publication date the fetchers can parse.
+	DatedItems int
+	Newest     time.Time
+	// Announced reports whether the page links to the feed, rather than it being at a common path.
+	Announced bool
+	// Err explains why an announced link is not a usable feed; nil for valid feeds.
+	Err error
+}
+
+// FindFeedLinks returns the feeds a page announces with <link rel="alternate">, resolved against pageURL.
+func FindFeedLinks(r io.Reader, pageURL *url.URL) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing page: %w", err)
+	}
+	var links []string
+	doc.Find("link[rel][href]").Each(func(_ int, link *goquery.Selection) {
+		rel := strings.Fields(strings.ToLower(link.AttrOr("rel", "")))
+		mediaType := strings.ToLower(strings.TrimSpace(link.AttrOr("type", "")))
+		if !slices.Contains(rel, "alternate") || !feedLinkTypes[mediaType] {
+			return
+		}
+		if href := resolveURL(pageURL, link.AttrOr("href", "")); href != "" && !slices.Contains(links, href) {
+			links = append(links, href)
+		}
+	})
+	return links, nil
+}
+
+// DiscoverFeeds finds the feeds of a website: the page itself if it is a feed, those the page
+// announces and those at common paths of the site. Every candidate is fetched and parsed; valid
+// feeds come first, the announced ones and those with more dated items before others. Candidates
+// at common paths that are not feeds are left out. The requests use options, which may be nil.
+func DiscoverFeeds(siteURL string, maxBodySize int64, options *HTTPOptions) ([]FeedCandidate, error) {
+	body, pageURL, err := fetchForDiscovery(siteURL, maxBodySize, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []FeedCandidate
+	seen := map[string]bool{siteURL: true, pageURL.String(): true}
+	if candidate, err := parseFeedCandidate(pageURL.String(), body); err == nil {
+		candidate.Announced = true
+		candidates = append(candidates, candidate)
+	} else {
+		links, _ := FindFeedLinks(bytes.NewReader(body), pageURL)
+		for _, link := range links {
+			seen[link] = true
+			candidate := checkFeedCandidate(link, maxBodySize, options)
+			candidate.Announced = true
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	root := &url.URL{Scheme: pageURL.Scheme, Host: pageURL.Host}
+	for _, path := range commonFeedPaths {
+		link := resolveURL(root, path)
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		if candidate := checkFeedCandidate(link, maxBodySize, options); candidate.Err == nil {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if a.Announced != b.Announced {
+			return a.Announced
+		}
+		return a.DatedItems > b.DatedItems
+	})
+	return candidates, nil
+}
+
+// fetchForDiscovery fetches a document, returning its body and its URL after redirects.
+func fetchForDiscovery(link string, maxBodySize int64, options *HTTPOptions) ([]byte, *url.URL, error) {
+	client, req, err := createHTTPRequest(link, "", options)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.CloseIdleConnections()
+	return getDocument(client, req, maxBodySize, "")
+}
+
+// checkFeedCandidate fetches and parses a possible feed.
+func checkFeedCandidate(link string, maxBodySize int64, options *HTTPOptions) FeedCandidate {
+	body, _, err := fetchForDiscovery(link, maxBodySize, options)
+	if err != nil {
+		return FeedCandidate{URL: link, Err: err}
+	}
+	candidate, err := parseFeedCandidate(link, body)
+	if err != nil {
+		return FeedCandidate{URL: link, Err: err}
+	}
+	return candidate
+}
+
+// parseFeedCandidate parses a document as a feed and counts its items and parseable dates.
+func parseFeedCandidate(link string, body []byte) (FeedCandidate, error) {
+	if gofeed.DetectFeedType(bytes.NewReader(body)) == gofeed.FeedTypeUnknown {
+		return FeedCandidate{}, fmt.Errorf("not a feed")
+	}
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		return FeedCandidate{}, fmt.Errorf("error parsing feed: %w", err)
+	}
+
+	dates, err := NewDateParser(nil, nil)
+	if err != nil {
+		return FeedCandidate{}, err
+	}
+	candidate := FeedCandidate{URL: link, Title: feed.Title, Format: feed.FeedType, Items: len(feed.Items)}
+	for _, item := range feed.Items {
+		published := item.PublishedParsed
+		if published == nil && item.Published != "" {
+			if t, err := dates.Parse(item.Published); err == nil {
+				published = &t
+			}
+		}
+		if published == nil {
+			continue
+		}
+		candidate.DatedItems++
+		if published.After(candidate.Newest) {
+			candidate.Newest = *published
+		}
+	}
+	return candidate, nil
+}
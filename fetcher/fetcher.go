@@ -1,6 +1,7 @@
 package fetcher
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,17 +10,37 @@ import (
 	"time"
 
 	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
 	"news/utils"
 )
 
 // NewsItem represents a single news article.
 type NewsItem struct {
+	GUID        string // Feed-provided unique ID, or the link if the feed has none
 	Title       string
 	Link        string
 	Content     string // Cleaned content
 	RawContent  string // Raw content with HTML
 	PublishedOn time.Time
-	ImageURL    string
+	Undated     bool      // PublishedOn is the fetch time, as the item has no date
+	UpdatedOn   time.Time // Zero if the feed does not report updates
+	ImageURL    string    // Main image, also listed in Media
+	Media       []Media
+	Categories  []string
+	Authors     []string
+	Language    string // Language of the feed, if declared
+	SourceName  string // Configured name of the news source
+	FeedTitle   string
+	Score       int    // Community score, e.g. upvotes, on sites that have one
+	Comments    int    // Number of comments on sites that have them
+	CommentsURL string // Discussion page, if it differs from the link
+}
+
+// Media is an image, video or audio file attached to a news item.
+type Media struct {
+	URL    string
+	Type   string // MIME type, e.g. "image/jpeg", if known
+	Medium string // "image", "video" or "audio", if known
 }
 
 // Fetcher is an interface for fetching news.
@@ -62,71 +83,121 @@ func extractImageURL(item *gofeed.Item) string {
 	return ""
 }
 
-// createHTTPRequest creates a standardized HTTP client and request for RSS fetching.
-func createHTTPRequest(url string) (*http.Client, *http.Request, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// extractMedia collects all media attached to a gofeed.Item, without duplicates.
+func extractMedia(item *gofeed.Item) []Media {
+	var media []Media
+	seen := make(map[string]bool)
+	add := func(m Media) {
+		if m.URL == "" || seen[m.URL] {
+			return
+		}
+		if m.Medium == "" {
+			m.Medium, _, _ = strings.Cut(m.Type, "/")
+		}
+		seen[m.URL] = true
+		media = append(media, m)
 	}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	addMediaExtensions := func(extensions map[string][]ext.Extension) {
+		for _, content := range extensions["content"] {
+			add(Media{URL: content.Attrs["url"], Type: content.Attrs["type"], Medium: content.Attrs["medium"]})
+		}
+		for _, thumbnail := range extensions["thumbnail"] {
+			add(Media{URL: thumbnail.Attrs["url"], Medium: "image"})
+		}
 	}
 
-	// Set browser-like headers to avoid being blocked.
-	userAgent := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36"
-	accept := "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9"
-	acceptLang := "en-US,en;q=0.9"
-
-	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Accept", accept)
-	req.Header.Set("Accept-Language", acceptLang)
-
-	return client, req, nil
+	if extensions, ok := item.Extensions["media"]; ok {
+		addMediaExtensions(extensions)
+		for _, group := range extensions["group"] {
+			addMediaExtensions(group.Children)
+		}
+	}
+	if item.Image != nil {
+		add(Media{URL: item.Image.URL, Medium: "image"})
+	}
+	for _, enclosure := range item.Enclosures {
+		add(Media{URL: enclosure.URL, Type: enclosure.Type})
+	}
+	return media
 }
 
-// newFeedParser creates a new gofeed.Parser with a custom User-Agent.
-func newFeedParser() *gofeed.Parser {
-	fp := gofeed.NewParser()
-	fp.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36"
-	return fp
+// newNewsItem converts a gofeed.Item published at publishedOn into a NewsItem.
+func newNewsItem(feed *gofeed.Feed, item *gofeed.Item, publishedOn time.Time, sourceName string) NewsItem {
+	content := ""
+	if item.Content != "" {
+		content = item.Content
+	} else if item.Description != "" {
+		content = item.Description
+	}
+
+	newsItem := NewsItem{
+		GUID:        item.GUID,
+		Title:       item.Title,
+		Link:        item.Link,
+		Content:     cleanHTML(content),
+		RawContent:  content, // Keep raw content
+		PublishedOn: publishedOn,
+		ImageURL:    extractImageURL(item),
+		Media:       extractMedia(item),
+		Categories:  item.Categories,
+		Language:    feed.Language,
+		SourceName:  sourceName,
+		FeedTitle:   feed.Title,
+	}
+	if newsItem.GUID == "" {
+		newsItem.GUID = item.Link
+	}
+	if item.UpdatedParsed != nil {
+		newsItem.UpdatedOn = *item.UpdatedParsed
+	}
+	for _, author := range item.Authors {
+		if author != nil && author.Name != "" {
+			newsItem.Authors = append(newsItem.Authors, author.Name)
+		}
+	}
+	if len(newsItem.Authors) == 0 && item.DublinCoreExt != nil {
+		newsItem.Authors = item.DublinCoreExt.Creator
+	}
+	return newsItem
 }
 
-// GenericFetcher is a fetcher for standard RSS feeds.
-type GenericFetcher struct {
-	URL string
+// RSSFetcher is a fetcher for RSS and Atom feeds.
+type RSSFetcher struct {
+	URL        string
+	SourceName string
+	// Dates parses publication dates the feed library does not understand; nil uses the built-in layouts.
+	Dates *DateParser
+	// Undated is the policy for items without a parseable publication date; UndatedSkip by default.
+	Undated string
+	// Charset overrides the encoding declared by the server or the feed, e.g. "windows-1251".
+	Charset string
+	// MaxBodySize limits the size of the feed in bytes; DefaultMaxBodySize if not positive.
+	MaxBodySize int64
+	// HTTP sets the proxy, headers and other request options; nil uses the defaults.
+	HTTP *HTTPOptions
 }
 
 // Fetch fetches news from the feed.
-func (f *GenericFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
-	var newsItems []NewsItem
-	var err error
-
-	newsItems, err = utils.Retry(attempts, delay, func() ([]NewsItem, error) {
-		client, req, err := createHTTPRequest(f.URL)
+func (f *RSSFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
+	return utils.Retry(attempts, delay, func() ([]NewsItem, error) {
+		client, req, err := createHTTPRequest(f.URL, "", f.HTTP)
 		if err != nil {
 			return nil, err
 		}
 		defer client.CloseIdleConnections()
 		return f.performFetch(client, req, since)
 	})
-
-	return newsItems, err
 }
 
 // performFetch performs the actual fetching and parsing logic.
-func (f *GenericFetcher) performFetch(client *http.Client, req *http.Request, since time.Time) ([]NewsItem, error) {
-	resp, err := client.Do(req)
+func (f *RSSFetcher) performFetch(client *http.Client, req *http.Request, since time.Time) ([]NewsItem, error) {
+	body, _, err := getDocument(client, req, f.MaxBodySize, f.Charset)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching or parsing feed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch feed, status code: %d", resp.StatusCode)
+		return nil, err
 	}
 
 	fp := gofeed.NewParser()
-	feed, err := fp.Parse(resp.Body)
+	feed, err := fp.Parse(bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("error parsing feed: %w", err)
 	}
@@ -134,137 +205,57 @@ func (f *GenericFetcher) performFetch(client *http.Client, req *http.Request, si
 	fmt.Printf("Fetching news from: %s\n", feed.Title)
 	fmt.Println("------------------------------")
 
+	dates := f.Dates
+	if dates == nil {
+		if dates, err = NewDateParser(nil, nil); err != nil {
+			return nil, err
+		}
+	}
+
 	var newsItems []NewsItem
+	fetchedAt := time.Now()
 
 	for _, item := range feed.Items {
-		publishedTime := item.PublishedParsed
-		if publishedTime == nil {
+		publishedTime, undated, ok := f.publishedTime(item, dates, fetchedAt)
+		if !ok {
 			log.Printf("Could not determine publication date for: %s", item.Title)
 			continue
 		}
 
 		if publishedTime.After(since) {
-			content := ""
-			if item.Content != "" {
-				content = item.Content
-			} else if item.Description != "" {
-				content = item.Description
-			}
-
-			imageURL := extractImageURL(item)
-
-			newsItems = append(newsItems, NewsItem{
-				Title:       item.Title,
-				Link:        item.Link,
-				Content:     cleanHTML(content),
-				RawContent:  content, // Keep raw content
-				PublishedOn: *publishedTime,
-				ImageURL:    imageURL,
-			})
+			newsItem := newNewsItem(feed, item, publishedTime, f.SourceName)
+			newsItem.Undated = undated
+			newsItems = append(newsItems, newsItem)
 		}
 	}
 	return newsItems, nil
 }
 
-// SvtvFetcher is a custom fetcher for svtv.org.
-type SvtvFetcher struct {
-	URL string
-}
-
-var russianDateReplacer = strings.NewReplacer(
-	"Янв", "Jan", "Фев", "Feb", "Мар", "Mar", "Апр", "Apr", "Май", "May", "Июн", "Jun",
-	"Июл", "Jul", "Авг", "Aug", "Сен", "Sep", "Окт", "Oct", "Ноя", "Nov", "Дек", "Dec",
-	"Пн", "Mon", "Вт", "Tue", "Ср", "Wed", "Чт", "Thu", "Пт", "Fri", "Сб", "Sat", "Вс", "Sun",
-)
-
-func parseRussianDate(dateStr string) (*time.Time, error) {
-	englishDateStr := russianDateReplacer.Replace(dateStr)
-	parsedTime, err := time.Parse(time.RFC1123Z, englishDateStr)
-	if err != nil {
-		return nil, err
+// publishedTime determines when an item was published, applying the undated item policy
+// if the feed gives no usable publication date. undated reports that the time is the fetch time.
+func (f *RSSFetcher) publishedTime(item *gofeed.Item, dates *DateParser, fetchedAt time.Time) (t time.Time, undated, ok bool) {
+	if item.PublishedParsed != nil {
+		return *item.PublishedParsed, false, true
 	}
-	return &parsedTime, nil
-}
-
-// Fetch fetches news from the svtv.org feed, handling its custom date format.
-func (f *SvtvFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
-	var newsItems []NewsItem
-	var err error
-
-	newsItems, err = utils.Retry(attempts, delay, func() ([]NewsItem, error) {
-		client, req, err := createHTTPRequest(f.URL)
-		if err != nil {
-			return nil, err
+	if item.Published != "" {
+		if t, err := dates.Parse(item.Published); err == nil {
+			return t, false, true
 		}
-		defer client.CloseIdleConnections()
-		return f.performSvtvFetch(client, req, since)
-	})
-
-	return newsItems, err
-}
-
-// performSvtvFetch performs the actual fetching and parsing logic with custom date parsing.
-func (f *SvtvFetcher) performSvtvFetch(client *http.Client, req *http.Request, since time.Time) ([]NewsItem, error) {
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching or parsing feed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch feed, status code: %d", resp.StatusCode)
-	}
-
-	fp := newFeedParser()
-	feed, err := fp.Parse(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing feed: %w", err)
+		log.Printf("Could not parse date '%s' for: %s", item.Published, item.Title)
 	}
 
-	fmt.Printf("Fetching news from: %s\n", feed.Title)
-	fmt.Println("------------------------------")
-
-	var newsItems []NewsItem
-
-	for _, item := range feed.Items {
-		var publishedTime *time.Time
-		// Try parsing with the library first
-		if item.PublishedParsed != nil {
-			publishedTime = item.PublishedParsed
-		} else if item.Published != "" {
-			// Fallback to manual parsing for Russian dates
-			parsedTime, err := parseRussianDate(item.Published)
-			if err != nil {
-				log.Printf("Could not parse date '%s' for: %s", item.Published, item.Title)
-				continue
-			}
-			publishedTime = parsedTime
+	switch f.Undated {
+	case UndatedFetchTime:
+		return fetchedAt, true, true
+	case UndatedUpdated:
+		if item.UpdatedParsed != nil {
+			return *item.UpdatedParsed, false, true
 		}
-
-		if publishedTime == nil {
-			log.Printf("Could not determine publication date for: %s", item.Title)
-			continue
-		}
-
-		if publishedTime.After(since) {
-			content := ""
-			if item.Content != "" {
-				content = item.Content
-			} else if item.Description != "" {
-				content = item.Description
+		if item.Updated != "" {
+			if t, err := dates.Parse(item.Updated); err == nil {
+				return t, false, true
 			}
-
-			imageURL := extractImageURL(item)
-
-			newsItems = append(newsItems, NewsItem{
-				Title:       item.Title,
-				Link:        item.Link,
-				Content:     cleanHTML(content),
-				RawContent:  content,
-				PublishedOn: *publishedTime,
-				ImageURL:    imageURL,
-			})
 		}
 	}
-	return newsItems, nil
-}
\ No newline at end of file
+	return time.Time{}, false, false
+}
@@ -0,0 +1,130 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FilterRules match news items by keywords, regular expressions, categories and link paths.
+// An item matches the rules if it matches any one of them.
+type FilterRules struct {
+	// Keywords are matched case-insensitively against the title and content.
+	Keywords []string `json:"keywords,omitempty"`
+	// Patterns are regular expressions matched against the title and content.
+	Patterns []string `json:"patterns,omitempty"`
+	// Categories are matched case-insensitively against the item's categories.
+	Categories []string `json:"categories,omitempty"`
+	// Paths are regular expressions matched against the path of the item's link.
+	Paths []string `json:"paths,omitempty"`
+
+	patterns []*regexp.Regexp
+	paths    []*regexp.Regexp
+}
+
+// Filter decides which fetched items are passed on for analysis. If any include rules are set,
+// only items matching one of them are kept; items matching an exclude rule are always dropped.
+// Community signals, on sites that report them, then drop little-noticed items and rank the rest.
+type Filter struct {
+	Include FilterRules `json:"include"`
+	Exclude FilterRules `json:"exclude"`
+	// MinScore and MinComments drop items with a lower score or fewer comments.
+	MinScore    int `json:"min_score,omitempty"`
+	MinComments int `json:"min_comments,omitempty"`
+	// Top keeps only this many items with the highest scores; all items if zero.
+	Top int `json:"top,omitempty"`
+}
+
+// Compile validates the filter's regular expressions. It must be called before Apply.
+func (f *Filter) Compile() error {
+	if err := f.Include.compile(); err != nil {
+		return fmt.Errorf("include: %w", err)
+	}
+	if err := f.Exclude.compile(); err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	if f.Top < 0 {
+		return fmt.Errorf("top must not be negative")
+	}
+	return nil
+}
+
+// Apply returns the items that pass the filter and the number of items dropped.
+func (f *Filter) Apply(items []NewsItem) ([]NewsItem, int) {
+	var kept []NewsItem
+	for _, item := range items {
+		if item.Score < f.MinScore || item.Comments < f.MinComments {
+			continue
+		}
+		if f.Include.empty() || f.Include.match(item) {
+			if !f.Exclude.match(item) {
+				kept = append(kept, item)
+			}
+		}
+	}
+	if f.Top > 0 && len(kept) > f.Top {
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].Score > kept[j].Score })
+		kept = kept[:f.Top]
+	}
+	return kept, len(items) - len(kept)
+}
+
+// compile compiles the regular expressions of the rules.
+func (r *FilterRules) compile() error {
+	r.patterns, r.paths = nil, nil
+	for _, pattern := range r.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	for _, pattern := range r.Paths {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+		r.paths = append(r.paths, re)
+	}
+	return nil
+}
+
+// empty reports whether no rules are set.
+func (r *FilterRules) empty() bool {
+	return len(r.Keywords) == 0 && len(r.Patterns) == 0 && len(r.Categories) == 0 && len(r.Paths) == 0
+}
+
+// match reports whether the item matches any of the rules.
+func (r *FilterRules) match(item NewsItem) bool {
+	text := item.Title + "\n" + item.Content
+	lowerText := strings.ToLower(text)
+	for _, keyword := range r.Keywords {
+		if strings.Contains(lowerText, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	for _, category := range r.Categories {
+		for _, itemCategory := range item.Categories {
+			if strings.EqualFold(strings.TrimSpace(itemCategory), category) {
+				return true
+			}
+		}
+	}
+	if len(r.paths) > 0 {
+		if u, err := url.Parse(item.Link); err == nil {
+			for _, re := range r.paths {
+				if re.MatchString(u.Path) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+// filterItems are items with the text, categories, links and community signals filters look at.
+var filterItems = []NewsItem{
+	{GUID: "election", Title: "Election results are in", Content: "Turnout was high.", Link: "https://news.example/politics/election", Categories: []string{"Politics"}, Score: 900, Comments: 300},
+	{GUID: "match", Title: "Local team wins the cup", Link: "https://news.example/sport/cup", Categories: []string{" Sport "}, Score: 150, Comments: 20},
+	{GUID: "ad", Title: "Sponsored: the best mattress", Content: "Buy now.", Link: "https://news.example/partner/mattress", Score: 5, Comments: 0},
+	{GUID: "budget", Title: "Parliament debates the BUDGET", Link: "https://news.example/politics/budget?page=2", Score: 400, Comments: 90},
+	{GUID: "weather", Title: "Storm expected tonight", Content: "Winds up to 120 km/h.", Link: "https://news.example/weather/storm", Categories: []string{"Weather"}, Score: 400, Comments: 10},
+}
+
+func TestFilterApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"empty", Filter{}, []string{"election", "match", "ad", "budget", "weather"}},
+		{"keyword ignores case", Filter{Include: FilterRules{Keywords: []string{"budget"}}}, []string{"budget"}},
+		{"keyword in content", Filter{Include: FilterRules{Keywords: []string{"turnout"}}}, []string{"election"}},
+		{"pattern", Filter{Include: FilterRules{Patterns: []string{`\d+ km/h`}}}, []string{"weather"}},
+		{"pattern is case-sensitive", Filter{Include: FilterRules{Patterns: []string{`storm`}}}, nil},
+		{"category ignores case and spaces", Filter{Include: FilterRules{Categories: []string{"sport"}}}, []string{"match"}},
+		{"path ignores the query", Filter{Include: FilterRules{Paths: []string{`^/politics/`}}}, []string{"election", "budget"}},
+		{"any include rule", Filter{Include: FilterRules{Keywords: []string{"storm"}, Categories: []string{"Sport"}}}, []string{"match", "weather"}},
+		{"exclude", Filter{Exclude: FilterRules{Keywords: []string{"sponsored"}, Paths: []string{`^/partner/`}}}, []string{"election", "match", "budget", "weather"}},
+		{"exclude wins over include", Filter{Include: FilterRules{Paths: []string{`^/politics/`}}, Exclude: FilterRules{Keywords: []string{"debates"}}}, []string{"election"}},
+		{"min score", Filter{MinScore: 400}, []string{"election", "budget", "weather"}},
+		{"min comments", Filter{MinComments: 20}, []string{"election", "match", "budget"}},
+		{"min score and comments", Filter{MinScore: 100, MinComments: 50}, []string{"election", "budget"}},
+		// Top ranks by score, keeping the order of items with equal scores
+		{"top", Filter{Top: 3}, []string{"election", "budget", "weather"}},
+		{"top of the kept items", Filter{Exclude: FilterRules{Categories: []string{"politics"}}, Top: 2}, []string{"budget", "weather"}},
+		{"top above the item count", Filter{Top: 10}, []string{"election", "match", "ad", "budget", "weather"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Compile(); err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			items := append([]NewsItem(nil), filterItems...)
+			kept, dropped := tt.filter.Apply(items)
+			var got []string
+			for _, item := range kept {
+				got = append(got, item.GUID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept %q, want %q", got, tt.want)
+			}
+			if dropped != len(filterItems)-len(tt.want) {
+				t.Errorf("dropped %d, want %d", dropped, len(filterItems)-len(tt.want))
+			}
+		})
+	}
+}
+
+func TestFilterCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantErr bool
+	}{
+		{"valid", Filter{Include: FilterRules{Patterns: []string{`^\w+`}, Paths: []string{`^/news/`}}}, false},
+		{"invalid include pattern", Filter{Include: FilterRules{Patterns: []string{`(`}}}, true},
+		{"invalid exclude path", Filter{Exclude: FilterRules{Paths: []string{`[`}}}, true},
+		{"negative top", Filter{Top: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Compile(); (err != nil) != tt.wantErr {
+				t.Errorf("Compile() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
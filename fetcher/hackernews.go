@@ -0,0 +1,179 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"news/utils"
+)
+
+// HackerNewsAPI is the official Hacker News API.
+const HackerNewsAPI = "https://hacker-news.firebaseio.com/v0"
+
+// hackerNewsStoryLimit is the number of stories read from the top of a list, one request each.
+const hackerNewsStoryLimit = 30
+
+// hackerNewsLists maps the Hacker News pages to the API's story lists.
+var hackerNewsLists = map[string]string{
+	"":       "topstories",
+	"news":   "topstories",
+	"top":    "topstories",
+	"best":   "beststories",
+	"newest": "newstories",
+	"new":    "newstories",
+	"ask":    "askstories",
+	"show":   "showstories",
+}
+
+// hackerNewsItem is the subset of a Hacker News item the fetcher uses.
+type hackerNewsItem struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	By          string `json:"by"`
+	Time        int64  `json:"time"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Text        string `json:"text"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+	Dead        bool   `json:"dead"`
+	Deleted     bool   `json:"deleted"`
+}
+
+// HackerNewsList returns the API story list of a Hacker News page given as its name, e.g. "best",
+// or its link, e.g. "https://news.ycombinator.com/show". The front page lists the top stories.
+func HackerNewsList(page string) (string, error) {
+	name := strings.TrimSpace(page)
+	if strings.Contains(name, "news.ycombinator.com") {
+		u, err := url.Parse(name)
+		if err != nil {
+			return "", fmt.Errorf("invalid Hacker News link %q", page)
+		}
+		name = strings.Trim(u.Path, "/")
+	}
+	list, ok := hackerNewsLists[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown Hacker News page %q", page)
+	}
+	return list, nil
+}
+
+// HackerNewsFetcher reads the stories of a Hacker News page, with their points and comment counts.
+type HackerNewsFetcher struct {
+	// Page is the page to read, see HackerNewsList.
+	Page        string
+	SourceName  string
+	MaxBodySize int64
+	HTTP        *HTTPOptions
+}
+
+// Fetch fetches the page's stories published after since.
+func (f *HackerNewsFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
+	list, err := HackerNewsList(f.Page)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Retry(attempts, delay, func() ([]NewsItem, error) {
+		client, err := NewHTTPClient(f.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		defer client.CloseIdleConnections()
+
+		var ids []int
+		if err := f.get(client, HackerNewsAPI+"/"+list+".json", &ids); err != nil {
+			return nil, err
+		}
+		if len(ids) > hackerNewsStoryLimit {
+			ids = ids[:hackerNewsStoryLimit]
+		}
+
+		slog.Info("Fetching news", "source", f.SourceName, "list", list)
+
+		// A story that fails to load is skipped rather than failing the others
+		var stories []hackerNewsItem
+		var lastErr error
+		for _, id := range ids {
+			var story hackerNewsItem
+			if err := f.get(client, fmt.Sprintf("%s/item/%d.json", HackerNewsAPI, id), &story); err != nil {
+				log.Printf("Skipping Hacker News story %d: %v", id, err)
+				lastErr = err
+				continue
+			}
+			stories = append(stories, story)
+		}
+		if len(stories) == 0 && lastErr != nil {
+			return nil, lastErr
+		}
+		return hackerNewsStories(stories, since, f.SourceName), nil
+	})
+}
+
+// get performs a GET request to the API and decodes the JSON response into v.
+func (f *HackerNewsFetcher) get(client *http.Client, apiURL string, v any) error {
+	req, err := newRequest(apiURL, "application/json", f.HTTP)
+	if err != nil {
+		return err
+	}
+	req = skipRobots(req)
+	body, _, err := getDocument(client, req, f.MaxBodySize, "")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error parsing %s: %w", apiURL, err)
+	}
+	return nil
+}
+
+// ParseHackerNewsItems extracts the stories published after since from a JSON array of items
+// as the API returns them one by one.
+func ParseHackerNewsItems(r io.Reader, since time.Time, sourceName string) ([]NewsItem, error) {
+	var stories []hackerNewsItem
+	if err := json.NewDecoder(r).Decode(&stories); err != nil {
+		return nil, fmt.Errorf("error parsing items: %w", err)
+	}
+	return hackerNewsStories(stories, since, sourceName), nil
+}
+
+// hackerNewsStories converts stories to news items, leaving out dead, deleted and old ones.
+// Stories without a link, such as Ask HN, link to their discussion.
+func hackerNewsStories(stories []hackerNewsItem, since time.Time, sourceName string) []NewsItem {
+	var newsItems []NewsItem
+	for _, story := range stories {
+		publishedOn := time.Unix(story.Time, 0)
+		if story.Type != "story" || story.Dead || story.Deleted || story.Title == "" || !publishedOn.After(since) {
+			continue
+		}
+		commentsURL := "https://news.ycombinator.com/item?id=" + strconv.Itoa(story.ID)
+		item := NewsItem{
+			GUID:        commentsURL,
+			Title:       story.Title,
+			Link:        story.URL,
+			Content:     htmlToText(story.Text),
+			RawContent:  story.Text,
+			PublishedOn: publishedOn,
+			SourceName:  sourceName,
+			FeedTitle:   "Hacker News",
+			Score:       story.Score,
+			Comments:    story.Descendants,
+			CommentsURL: commentsURL,
+		}
+		if item.Link == "" {
+			item.Link = commentsURL
+		}
+		if story.By != "" {
+			item.Authors = []string{story.By}
+		}
+		newsItems = append(newsItems, item)
+	}
+	return newsItems
+}
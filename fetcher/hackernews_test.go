@@ -0,0 +1,89 @@
+package fetcher
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseHackerNewsItems(t *testing.T) {
+	file, err := os.Open("testdata/hackernews_items.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	since := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	items, err := ParseHackerNewsItems(file, since, "HN")
+	if err != nil {
+		t.Fatalf("ParseHackerNewsItems: %v", err)
+	}
+
+	// Jobs, dead, deleted, missing and old items are left out
+	want := []NewsItem{
+		{
+			GUID:        "https://news.ycombinator.com/item?id=41800001",
+			Title:       "A new open-source database beats the benchmarks",
+			Link:        "https://db.example/blog/launch",
+			PublishedOn: time.Unix(1792220400, 0),
+			Authors:     []string{"pg_fan"},
+			SourceName:  "HN",
+			FeedTitle:   "Hacker News",
+			Score:       845,
+			Comments:    312,
+			CommentsURL: "https://news.ycombinator.com/item?id=41800001",
+		},
+		{
+			// A story without a link links to its discussion
+			GUID:        "https://news.ycombinator.com/item?id=41800002",
+			Title:       "Ask HN: How do you keep up with systems research?",
+			Link:        "https://news.ycombinator.com/item?id=41800002",
+			Content:     "I'm looking for advice.\n\nWhat do you read to keep up with systems research?",
+			PublishedOn: time.Unix(1792224000, 0),
+			Authors:     []string{"curious"},
+			SourceName:  "HN",
+			FeedTitle:   "Hacker News",
+			Score:       120,
+			Comments:    57,
+			CommentsURL: "https://news.ycombinator.com/item?id=41800002",
+		},
+	}
+	compareItems(t, items, want)
+	if raw := items[1].RawContent; raw != "I&#x27;m looking for advice.<p>What do you read to keep up with <i>systems</i> research?" {
+		t.Errorf("RawContent = %q, want the story's text as is", raw)
+	}
+}
+
+func TestHackerNewsList(t *testing.T) {
+	tests := []struct {
+		page    string
+		want    string
+		wantErr bool
+	}{
+		{page: "", want: "topstories"},
+		{page: "best", want: "beststories"},
+		{page: "Show", want: "showstories"},
+		{page: "https://news.ycombinator.com/", want: "topstories"},
+		{page: "https://news.ycombinator.com/newest", want: "newstories"},
+		{page: "https://news.ycombinator.com/ask/", want: "askstories"},
+		{page: "jobs", wantErr: true},
+		{page: "https://news.ycombinator.com/item?id=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			got, err := HackerNewsList(tt.page)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("HackerNewsList(%q) = %q, want an error", tt.page, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HackerNewsList(%q): %v", tt.page, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HackerNewsList(%q) = %q, want %q", tt.page, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"news/utils"
+)
+
+// HTMLSelectors are the CSS selectors locating articles on a page. All selectors except Item
+// are applied within each article element.
+type HTMLSelectors struct {
+	// Item matches each article in the list.
+	Item string `json:"item"`
+	// Title matches the element whose text is the headline.
+	Title string `json:"title"`
+	// Link matches the element whose href is the article link; the first link in the article if empty.
+	Link string `json:"link,omitempty"`
+	// Date matches the element whose datetime attribute, or text, is the publication date.
+	Date string `json:"date,omitempty"`
+	// Image matches the img element of the article picture.
+	Image string `json:"image,omitempty"`
+	// Content matches the element whose text is the summary.
+	Content string `json:"content,omitempty"`
+}
+
+// Validate checks that the required selectors are set and all selectors are valid.
+func (s *HTMLSelectors) Validate() error {
+	if s.Item == "" || s.Title == "" {
+		return fmt.Errorf("item and title selectors are required")
+	}
+	for _, selector := range []string{s.Item, s.Title, s.Link, s.Date, s.Image, s.Content} {
+		if selector == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(selector); err != nil {
+			return fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+	}
+	return nil
+}
+
+// HTMLFetcher scrapes articles from a web page using CSS selectors, for sites without a feed.
+type HTMLFetcher struct {
+	URL        string
+	SourceName string
+	Selectors  HTMLSelectors
+	// Dates parses the publication dates; nil uses the built-in layouts.
+	Dates *DateParser
+	// Undated is the policy for articles without a parseable date; UndatedSkip by default.
+	Undated     string
+	Charset     string
+	MaxBodySize int64
+	HTTP        *HTTPOptions
+}
+
+// Fetch fetches the page and extracts the articles published after since.
+func (f *HTMLFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
+	return utils.Retry(attempts, delay, func() ([]NewsItem, error) {
+		client, req, err := createHTTPRequest(f.URL, "", f.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		defer client.CloseIdleConnections()
+		return f.performFetch(client, req, since)
+	})
+}
+
+// performFetch performs the actual fetching and scraping logic.
+func (f *HTMLFetcher) performFetch(client *http.Client, req *http.Request, since time.Time) ([]NewsItem, error) {
+	body, pageURL, err := getDocument(client, req, f.MaxBodySize, f.Charset)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing page: %w", err)
+	}
+
+	pageTitle := strings.TrimSpace(doc.Find("title").First().Text())
+	slog.Info("Fetching news", "source", f.SourceName, "page", pageTitle)
+
+	dates := f.Dates
+	if dates == nil {
+		if dates, err = NewDateParser(nil, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var newsItems []NewsItem
+	fetchedAt := time.Now()
+
+	doc.Find(f.Selectors.Item).Each(func(_ int, article *goquery.Selection) {
+		title := collapseSpace(article.Find(f.Selectors.Title).First().Text())
+		if title == "" {
+			return
+		}
+
+		publishedTime, undated, ok := f.publishedTime(article, dates, fetchedAt)
+		if !ok {
+			log.Printf("Could not determine publication date for: %s", title)
+			return
+		}
+		if !publishedTime.After(since) {
+			return
+		}
+
+		link := article.Find("a[href]").First()
+		if f.Selectors.Link != "" {
+			link = article.Find(f.Selectors.Link).First()
+		}
+		if link.Length() == 0 && article.Is("a[href]") {
+			link = article
+		}
+
+		item := NewsItem{
+			Title:       title,
+			Link:        resolveURL(pageURL, link.AttrOr("href", "")),
+			PublishedOn: publishedTime,
+			Undated:     undated,
+			SourceName:  f.SourceName,
+			FeedTitle:   pageTitle,
+		}
+		item.GUID = item.Link
+		if f.Selectors.Content != "" {
+			content := article.Find(f.Selectors.Content).First()
+			item.Content = collapseSpace(content.Text())
+			item.RawContent, _ = content.Html()
+		}
+		if f.Selectors.Image != "" {
+			item.ImageURL = resolveURL(pageURL, imageSource(article.Find(f.Selectors.Image).First()))
+			if item.ImageURL != "" {
+				item.Media = []Media{{URL: item.ImageURL, Medium: "image"}}
+			}
+		}
+		newsItems = append(newsItems, item)
+	})
+	return newsItems, nil
+}
+
+// publishedTime determines when an article was published, applying the undated item policy
+// if the page gives no usable date. undated reports that the time is the fetch time.
+func (f *HTMLFetcher) publishedTime(article *goquery.Selection, dates *DateParser, fetchedAt time.Time) (t time.Time, undated, ok bool) {
+	if f.Selectors.Date != "" {
+		date := article.Find(f.Selectors.Date).First()
+		value := date.AttrOr("datetime", date.AttrOr("content", date.Text()))
+		if t, err := dates.Parse(value); err == nil {
+			return t, false, true
+		} else if strings.TrimSpace(value) != "" {
+			log.Printf("Could not parse date '%s': %v", value, err)
+		}
+	}
+	if f.Undated == UndatedFetchTime {
+		return fetchedAt, true, true
+	}
+	return time.Time{}, false, false
+}
+
+// imageSource returns the address of an image, preferring lazy-loading attributes over placeholders.
+func imageSource(img *goquery.Selection) string {
+	for _, attr := range []string{"data-src", "data-lazy-src", "src"} {
+		if src := strings.TrimSpace(img.AttrOr(attr, "")); src != "" && !strings.HasPrefix(src, "data:") {
+			return src
+		}
+	}
+	if srcset := img.AttrOr("srcset", ""); srcset != "" {
+		first, _, _ := strings.Cut(strings.TrimSpace(srcset), ",")
+		src, _, _ := strings.Cut(strings.TrimSpace(first), " ")
+		return src
+	}
+	return ""
+}
+
+// resolveURL resolves a possibly relative reference against the page URL.
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(u).String()
+}
+
+// collapseSpace trims a text and collapses its runs of whitespace into single spaces.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// postTitleLimit is the length, in runes, of titles made from the first line of a post.
+const postTitleLimit = 120
+
+// firstLineTitle uses the first line of a social media post as its title, as posts have none.
+func firstLineTitle(content, fallback string) string {
+	firstLine, _, _ := strings.Cut(content, "\n")
+	if firstLine == "" {
+		return fallback
+	}
+	runes := []rune(firstLine)
+	if len(runes) > postTitleLimit {
+		return strings.TrimSpace(string(runes[:postTitleLimit-1])) + "…"
+	}
+	return firstLine
+}
+
+// htmlToText converts an HTML fragment to plain text, keeping paragraphs and line breaks.
+func htmlToText(fragment string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return cleanHTML(fragment)
+	}
+	doc.Find("br").ReplaceWithHtml("\n")
+	// Paragraphs are separated on both sides, as Hacker News opens them without closing them
+	doc.Find("p").Each(func(_ int, p *goquery.Selection) {
+		p.PrependHtml("\n\n")
+		p.AppendHtml("\n\n")
+	})
+
+	var lines []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		lines = append(lines, collapseSpace(line))
+	}
+	text := strings.Join(lines, "\n")
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,128 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// serveFixture serves a file from testdata at every path with the given content type.
+func serveFixture(t *testing.T, name, contentType string) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// htmlArticleSelectors locate the articles of testdata/html_articles.html.
+var htmlArticleSelectors = HTMLSelectors{
+	Item:    ".news",
+	Title:   "h2",
+	Link:    "h2 a, a.more",
+	Date:    "time, .date",
+	Image:   "img.thumb",
+	Content: ".lead",
+}
+
+func TestHTMLFetcher(t *testing.T) {
+	server := serveFixture(t, "html_articles.html", "text/html")
+	f := &HTMLFetcher{URL: server.URL + "/news/", SourceName: "Gazette", Selectors: htmlArticleSelectors}
+	items, err := f.Fetch(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), 1, 0)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	// Articles without a title or a usable date, and the one older than since, are left out
+	want := []NewsItem{
+		{
+			// Relative links are resolved, and lazy-loaded images preferred over placeholders
+			GUID:        server.URL + "/news/council-budget",
+			Title:       "Council approves the budget",
+			Link:        server.URL + "/news/council-budget",
+			Content:     "The new budget funds two schools and a bridge.",
+			PublishedOn: time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+			ImageURL:    server.URL + "/img/budget.jpg",
+			Media:       []Media{{URL: server.URL + "/img/budget.jpg", Medium: "image"}},
+			SourceName:  "Gazette",
+			FeedTitle:   "City Gazette — Latest news",
+		},
+		{
+			// A date given as text is parsed, and an image with only a srcset takes its first candidate
+			GUID:        "https://gazette.example/news/tram-repairs?utm_source=home",
+			Title:       "Tram line closes for repairs",
+			Link:        "https://gazette.example/news/tram-repairs?utm_source=home",
+			PublishedOn: time.Date(2026, 10, 17, 10, 15, 0, 0, time.Local),
+			ImageURL:    server.URL + "/img/tram-480.jpg",
+			Media:       []Media{{URL: server.URL + "/img/tram-480.jpg", Medium: "image"}},
+			SourceName:  "Gazette",
+			FeedTitle:   "City Gazette — Latest news",
+		},
+	}
+	compareItems(t, items, want)
+
+	if raw := items[0].RawContent; raw != "The new budget funds <b>two schools</b>\n        and a bridge." {
+		t.Errorf("RawContent = %q, want the summary's markup", raw)
+	}
+}
+
+func TestHTMLFetcherUndated(t *testing.T) {
+	server := serveFixture(t, "html_articles.html", "text/html")
+	f := &HTMLFetcher{URL: server.URL + "/news/", SourceName: "Gazette", Selectors: htmlArticleSelectors, Undated: UndatedFetchTime}
+	before := time.Now()
+	items, err := f.Fetch(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), 1, 0)
+	after := time.Now()
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item.Title)
+		dated := item.Title == "Council approves the budget" || item.Title == "Tram line closes for repairs"
+		if item.Undated == dated {
+			t.Errorf("%q: Undated = %v, want %v", item.Title, item.Undated, !dated)
+		}
+		if item.Undated && (item.PublishedOn.Before(before) || item.PublishedOn.After(after)) {
+			t.Errorf("%q: PublishedOn = %v, want the fetch time", item.Title, item.PublishedOn)
+		}
+	}
+	// An article that is itself the link links to its address
+	if len(items) > 2 && items[2].Link != server.URL+"/news/market" {
+		t.Errorf("Link = %q, want the card's address", items[2].Link)
+	}
+	want := []string{"Council approves the budget", "Tram line closes for repairs", "Farmers market moves to the square", "Storm warning issued"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles = %q, want %q", titles, want)
+	}
+}
+
+func TestHTMLSelectorsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors HTMLSelectors
+		wantErr   bool
+	}{
+		{"valid", htmlArticleSelectors, false},
+		{"only required", HTMLSelectors{Item: "article", Title: "h2"}, false},
+		{"no item", HTMLSelectors{Title: "h2"}, true},
+		{"no title", HTMLSelectors{Item: "article"}, true},
+		{"invalid selector", HTMLSelectors{Item: "article", Title: "h2", Date: "time["}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.selectors.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"news/utils"
+)
+
+// JSONFields map the fields of a JSON API response to news items with path expressions.
+// All paths except Items are evaluated within each item.
+type JSONFields struct {
+	// Items selects the list of items, e.g. "data.articles" or "$.results[*]".
+	Items      string `json:"items"`
+	Title      string `json:"title"`
+	Link       string `json:"link,omitempty"`
+	Content    string `json:"content,omitempty"`
+	Date       string `json:"date,omitempty"`
+	Image      string `json:"image,omitempty"`
+	GUID       string `json:"guid,omitempty"`
+	Categories string `json:"categories,omitempty"`
+	Authors    string `json:"authors,omitempty"`
+
+	paths map[string]JSONPath
+}
+
+// Compile validates the path expressions. It must be called before the fields are used.
+func (f *JSONFields) Compile() error {
+	if f.Items == "" || f.Title == "" {
+		return fmt.Errorf("items and title paths are required")
+	}
+	f.paths = make(map[string]JSONPath)
+	for name, expr := range map[string]string{
+		"items": f.Items, "title": f.Title, "link": f.Link, "content": f.Content, "date": f.Date,
+		"image": f.Image, "guid": f.GUID, "categories": f.Categories, "authors": f.Authors,
+	} {
+		if expr == "" {
+			continue
+		}
+		path, err := CompileJSONPath(expr)
+		if err != nil {
+			return err
+		}
+		f.paths[name] = path
+	}
+	return nil
+}
+
+// value returns the first value of the named field in the item.
+func (f *JSONFields) value(name string, item any) string {
+	if path, ok := f.paths[name]; ok {
+		return path.String(item)
+	}
+	return ""
+}
+
+// values returns all values of the named field in the item.
+func (f *JSONFields) values(name string, item any) []string {
+	if path, ok := f.paths[name]; ok {
+		return path.Strings(item)
+	}
+	return nil
+}
+
+// JSONFetcher fetches news from a JSON API, mapping its fields with path expressions.
+type JSONFetcher struct {
+	URL        string
+	SourceName string
+	// Fields must be compiled.
+	Fields JSONFields
+	// Dates parses the publication dates; nil uses the built-in layouts. Numeric dates are
+	// taken as Unix time in seconds or milliseconds.
+	Dates *DateParser
+	// Undated is the policy for items without a parseable date; UndatedSkip by default.
+	Undated     string
+	MaxBodySize int64
+	HTTP        *HTTPOptions
+}
+
+// Fetch fetches the API response and extracts the items published after since.
+func (f *JSONFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
+	return utils.Retry(attempts, delay, func() ([]NewsItem, error) {
+		client, req, err := createHTTPRequest(f.URL, "application/json", f.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		defer client.CloseIdleConnections()
+		return f.performFetch(client, req, since)
+	})
+}
+
+// performFetch performs the actual fetching and mapping logic.
+func (f *JSONFetcher) performFetch(client *http.Client, req *http.Request, since time.Time) ([]NewsItem, error) {
+	body, pageURL, err := getDocument(client, req, f.MaxBodySize, "")
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var root any
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+
+	dates := f.Dates
+	if dates == nil {
+		if dates, err = NewDateParser(nil, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	items := f.Fields.paths["items"].Eval(root)
+	if len(items) == 1 {
+		if list, ok := items[0].([]any); ok {
+			items = list
+		}
+	}
+
+	var newsItems []NewsItem
+	fetchedAt := time.Now()
+
+	for _, item := range items {
+		title := collapseSpace(f.Fields.value("title", item))
+		if title == "" {
+			continue
+		}
+
+		publishedTime, undated, ok := f.publishedTime(item, dates, fetchedAt)
+		if !ok {
+			log.Printf("Could not determine publication date for: %s", title)
+			continue
+		}
+		if !publishedTime.After(since) {
+			continue
+		}
+
+		content := f.Fields.value("content", item)
+		newsItem := NewsItem{
+			GUID:        f.Fields.value("guid", item),
+			Title:       title,
+			Link:        resolveURL(pageURL, f.Fields.value("link", item)),
+			Content:     cleanHTML(content),
+			RawContent:  content,
+			PublishedOn: publishedTime,
+			Undated:     undated,
+			ImageURL:    resolveURL(pageURL, f.Fields.value("image", item)),
+			Categories:  f.Fields.values("categories", item),
+			Authors:     f.Fields.values("authors", item),
+			SourceName:  f.SourceName,
+		}
+		if newsItem.GUID == "" {
+			newsItem.GUID = newsItem.Link
+		}
+		if newsItem.ImageURL != "" {
+			newsItem.Media = []Media{{URL: newsItem.ImageURL, Medium: "image"}}
+		}
+		newsItems = append(newsItems, newsItem)
+	}
+	return newsItems, nil
+}
+
+// publishedTime determines when an item was published, applying the undated item policy
+// if the item has no usable date. undated reports that the time is the fetch time.
+func (f *JSONFetcher) publishedTime(item any, dates *DateParser, fetchedAt time.Time) (t time.Time, undated, ok bool) {
+	if value := f.Fields.value("date", item); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			// Timestamps this large are in milliseconds
+			if seconds > 1e11 {
+				seconds /= 1000
+			}
+			return time.Unix(int64(seconds), 0), false, true
+		}
+		if t, err := dates.Parse(value); err == nil {
+			return t, false, true
+		}
+		log.Printf("Could not parse date '%s'", value)
+	}
+	if f.Undated == UndatedFetchTime {
+		return fetchedAt, true, true
+	}
+	return time.Time{}, false, false
+}
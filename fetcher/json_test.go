@@ -0,0 +1,131 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// jsonAPIFields map the articles of testdata/json_api.json.
+func jsonAPIFields(t *testing.T, items string) JSONFields {
+	t.Helper()
+	fields := JSONFields{
+		Items:      items,
+		Title:      "headline",
+		Link:       "url",
+		Content:    "body",
+		Date:       "published",
+		Image:      "media[0].url",
+		GUID:       "id",
+		Categories: "tags",
+		Authors:    "authors[*].name",
+	}
+	if err := fields.Compile(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return fields
+}
+
+func TestJSONFetcher(t *testing.T) {
+	server := serveFixture(t, "json_api.json", "application/json")
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	// The list of items is selected either as the array or as its elements
+	for _, path := range []string{"data.articles", "$.data.articles[*]"} {
+		t.Run(path, func(t *testing.T) {
+			f := &JSONFetcher{URL: server.URL + "/api/news", SourceName: "API", Fields: jsonAPIFields(t, path)}
+			items, err := f.Fetch(since, 1, 0)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+
+			// Items without a title or a usable date, and the one older than since, are left out
+			want := []NewsItem{
+				{
+					GUID:        "a-1",
+					Title:       "Central bank raises rates",
+					Link:        server.URL + "/articles/rates",
+					Content:     "The key rate goes up by 2 points.",
+					PublishedOn: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+					ImageURL:    "https://cdn.example/rates.jpg",
+					Media:       []Media{{URL: "https://cdn.example/rates.jpg", Medium: "image"}},
+					Categories:  []string{"economy", "banks"},
+					Authors:     []string{"Anna Petrova", "Ivan Sidorov"},
+					SourceName:  "API",
+				},
+				{
+					// Numbers are read as text, and dates as Unix time in seconds
+					GUID:        "102",
+					Title:       "Rocket launch moved to Friday",
+					Link:        "https://space.example/launch",
+					PublishedOn: time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC),
+					Categories:  []string{"space"},
+					SourceName:  "API",
+				},
+				{
+					// Without an ID the link identifies the item; dates may be in milliseconds
+					GUID:        server.URL + "/articles/bridge",
+					Title:       "Bridge reopens after repairs",
+					Link:        server.URL + "/articles/bridge",
+					PublishedOn: time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC),
+					ImageURL:    server.URL + "/images/bridge.jpg",
+					Media:       []Media{{URL: server.URL + "/images/bridge.jpg", Medium: "image"}},
+					SourceName:  "API",
+				},
+			}
+			compareItems(t, items, want)
+
+			if raw := items[0].RawContent; raw != "<p>The key rate goes up by <b>2 points</b>.</p>" {
+				t.Errorf("RawContent = %q, want the content as is", raw)
+			}
+		})
+	}
+}
+
+func TestJSONFetcherUndated(t *testing.T) {
+	server := serveFixture(t, "json_api.json", "application/json")
+	f := &JSONFetcher{URL: server.URL + "/api/news", SourceName: "API", Fields: jsonAPIFields(t, "data.articles"), Undated: UndatedFetchTime}
+	before := time.Now()
+	items, err := f.Fetch(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), 1, 0)
+	after := time.Now()
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	var undated []string
+	for _, item := range items {
+		if !item.Undated {
+			continue
+		}
+		undated = append(undated, item.GUID)
+		if item.PublishedOn.Before(before) || item.PublishedOn.After(after) {
+			t.Errorf("%s: PublishedOn = %v, want the fetch time", item.GUID, item.PublishedOn)
+		}
+	}
+	if want := []string{"a-6", "a-7"}; !reflect.DeepEqual(undated, want) {
+		t.Errorf("undated items = %q, want %q", undated, want)
+	}
+	if len(items) != 5 {
+		t.Errorf("got %d items, want the 3 dated and 2 undated ones", len(items))
+	}
+}
+
+func TestJSONFieldsCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  JSONFields
+		wantErr bool
+	}{
+		{"required only", JSONFields{Items: "items", Title: "title"}, false},
+		{"no items", JSONFields{Title: "title"}, true},
+		{"no title", JSONFields{Items: "items"}, true},
+		{"invalid path", JSONFields{Items: "items", Title: "title", Date: "dates[first]"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fields.Compile(); (err != nil) != tt.wantErr {
+				t.Errorf("Compile() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
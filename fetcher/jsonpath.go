@@ -0,0 +1,162 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathStep is a single step of a JSON path: an object key, an array index or a wildcard.
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// JSONPath is a compiled path expression selecting values in a decoded JSON document.
+// It supports a subset of JSONPath: "$.data.items[*]", "media[0].url", "tags[*].name",
+// "['key.with.dots']" and negative indices counting from the end.
+type JSONPath []pathStep
+
+// CompileJSONPath parses a path expression. The leading "$" is optional.
+func CompileJSONPath(path string) (JSONPath, error) {
+	var steps JSONPath
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			// A quoted key may contain brackets, so its bracket closes after the closing quote
+			if quoted := strings.TrimLeft(rest[1:], " "); quoted != "" && (quoted[0] == '\'' || quoted[0] == '"') {
+				if closing := strings.IndexByte(quoted[1:], quoted[0]); closing >= 0 {
+					afterQuote := len(rest) - len(quoted) + closing + 2
+					end = strings.IndexByte(rest[afterQuote:], ']')
+					if end >= 0 {
+						end += afterQuote
+					}
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in path %q", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in path %q", inner, path)
+				}
+				steps = append(steps, pathStep{index: index, isIndex: true})
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			if key == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else {
+				steps = append(steps, pathStep{key: key})
+			}
+		}
+	}
+	return steps, nil
+}
+
+// Eval returns all values the path selects in a document decoded into interface values.
+func (p JSONPath) Eval(root any) []any {
+	values := []any{root}
+	for _, step := range p {
+		var next []any
+		for _, value := range values {
+			switch v := value.(type) {
+			case map[string]any:
+				if step.wildcard {
+					keys := make([]string, 0, len(v))
+					for key := range v {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					for _, key := range keys {
+						next = append(next, v[key])
+					}
+				} else if child, ok := v[step.key]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []any:
+				switch {
+				case step.wildcard:
+					next = append(next, v...)
+				case step.isIndex:
+					index := step.index
+					if index < 0 {
+						index += len(v)
+					}
+					if index >= 0 && index < len(v) {
+						next = append(next, v[index])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// String returns the first scalar value the path selects as a string, or an empty string.
+func (p JSONPath) String(root any) string {
+	for _, value := range p.Eval(root) {
+		if s, ok := scalarString(value); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Strings returns all scalar values the path selects, flattening arrays.
+func (p JSONPath) Strings(root any) []string {
+	var result []string
+	var add func(value any)
+	add = func(value any) {
+		if list, ok := value.([]any); ok {
+			for _, element := range list {
+				add(element)
+			}
+			return
+		}
+		if s, ok := scalarString(value); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	for _, value := range p.Eval(root) {
+		add(value)
+	}
+	return result
+}
+
+// scalarString converts a JSON string, number or boolean to a string.
+func scalarString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
@@ -0,0 +1,158 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const jsonPathDocument = `{
+	"data": {
+		"items": [
+			{"title": "First", "media": [{"url": "a.jpg"}, {"url": "b.jpg"}], "tags": [{"name": "x"}, {"name": "y"}]},
+			{"title": "Second", "media": [], "score": 42, "pinned": true},
+			{"title": "Third", "media": [{"url": "c.jpg"}], "tags": ["plain"]}
+		]
+	},
+	"meta.version": "2",
+	"odd]key": {"x": 1},
+	"it's": "quoted",
+	"byId": {"b": {"title": "Bee"}, "a": {"title": "Ay"}, "c": {"title": "Sea"}}
+}`
+
+func TestCompileJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    JSONPath
+		wantErr bool
+	}{
+		{path: "", want: nil},
+		{path: "$", want: nil},
+		{path: "$.data.items", want: JSONPath{{key: "data"}, {key: "items"}}},
+		{path: "data.items", want: JSONPath{{key: "data"}, {key: "items"}}},
+		{path: "items[*].url", want: JSONPath{{key: "items"}, {wildcard: true}, {key: "url"}}},
+		{path: "items.*", want: JSONPath{{key: "items"}, {wildcard: true}}},
+		{path: "media[0]", want: JSONPath{{key: "media"}, {index: 0, isIndex: true}}},
+		{path: "media[-1]", want: JSONPath{{key: "media"}, {index: -1, isIndex: true}}},
+		{path: "media[ 2 ]", want: JSONPath{{key: "media"}, {index: 2, isIndex: true}}},
+		{path: "$['meta.version']", want: JSONPath{{key: "meta.version"}}},
+		{path: `$["meta.version"]`, want: JSONPath{{key: "meta.version"}}},
+		{path: "$['odd]key'].x", want: JSONPath{{key: "odd]key"}, {key: "x"}}},
+		{path: `$["it's"]`, want: JSONPath{{key: "it's"}}},
+		{path: "$['*']", want: JSONPath{{key: "*"}}},
+		{path: "$['']", want: JSONPath{{key: ""}}},
+		{path: "media[0", wantErr: true},
+		{path: "$['odd]key'", wantErr: true},
+		{path: "media[first]", wantErr: true},
+		{path: "media['unterminated]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := CompileJSONPath(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("CompileJSONPath(%q) = %v, want an error", tt.path, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompileJSONPath(%q): %v", tt.path, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompileJSONPath(%q) = %+v, want %+v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathEval(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(jsonPathDocument))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"$.data.items[*].title", []string{"First", "Second", "Third"}},
+		{"data.items.*.title", []string{"First", "Second", "Third"}},
+		{"data.items[0].media[*].url", []string{"a.jpg", "b.jpg"}},
+		{"data.items[*].media[0].url", []string{"a.jpg", "c.jpg"}},
+		{"data.items[-1].title", []string{"Third"}},
+		{"data.items[-3].title", []string{"First"}},
+		{"data.items[-4].title", nil},
+		{"data.items[3].title", nil},
+		{"data.items[0].media[-1].url", []string{"b.jpg"}},
+		{"data.items[*].score", []string{"42"}},
+		{"data.items[*].pinned", []string{"true"}},
+		{"data.items[0].tags[*].name", []string{"x", "y"}},
+		// Wildcards over objects go through the keys in sorted order
+		{"byId.*.title", []string{"Ay", "Bee", "Sea"}},
+		{"byId[*].title", []string{"Ay", "Bee", "Sea"}},
+		{"$['meta.version']", []string{"2"}},
+		{"$.meta.version", nil},
+		{"$['odd]key'].x", []string{"1"}},
+		{`$["it's"]`, []string{"quoted"}},
+		// Indices select nothing in objects, and keys nothing in arrays
+		{"byId[0]", nil},
+		{"data.items.title", nil},
+		{"data.missing.title", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := CompileJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("CompileJSONPath(%q): %v", tt.path, err)
+			}
+			var got []string
+			for _, value := range path.Eval(document) {
+				s, ok := scalarString(value)
+				if !ok {
+					t.Fatalf("Eval(%q) selected a non-scalar %v", tt.path, value)
+				}
+				got = append(got, s)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathStrings(t *testing.T) {
+	var document any
+	if err := json.Unmarshal([]byte(jsonPathDocument), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		first  string
+		values []string
+	}{
+		// Arrays are flattened and objects skipped
+		{"data.items[*].tags", "", []string{"plain"}},
+		{"data.items[*].tags[*]", "plain", []string{"plain"}},
+		{"data.items[*].media[*].url", "a.jpg", []string{"a.jpg", "b.jpg", "c.jpg"}},
+		{"data.items[1].score", "42", []string{"42"}},
+		{"data.missing", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := CompileJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("CompileJSONPath(%q): %v", tt.path, err)
+			}
+			if got := path.String(document); got != tt.first {
+				t.Errorf("String(%q) = %q, want %q", tt.path, got, tt.first)
+			}
+			if got := path.Strings(document); !reflect.DeepEqual(got, tt.values) {
+				t.Errorf("Strings(%q) = %q, want %q", tt.path, got, tt.values)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"news/utils"
+)
+
+// mastodonPageSize is the number of statuses requested, the most the API returns at once.
+const mastodonPageSize = 40
+
+// mastodonStatus is the subset of a Mastodon status the fetcher uses.
+type mastodonStatus struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	URL         string    `json:"url"`
+	URI         string    `json:"uri"`
+	Content     string    `json:"content"`
+	SpoilerText string    `json:"spoiler_text"`
+	Language    string    `json:"language"`
+	// InReplyToAccountID is the author of the status replied to, if the status is a reply.
+	InReplyToAccountID string `json:"in_reply_to_account_id"`
+	Account            struct {
+		ID          string `json:"id"`
+		Acct        string `json:"acct"`
+		DisplayName string `json:"display_name"`
+	} `json:"account"`
+	MediaAttachments []struct {
+		Type       string `json:"type"`
+		URL        string `json:"url"`
+		PreviewURL string `json:"preview_url"`
+	} `json:"media_attachments"`
+	Tags []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	Card *struct {
+		Image string `json:"image"`
+	} `json:"card"`
+	Reblog *mastodonStatus `json:"reblog"`
+}
+
+// MastodonTimeline identifies a public timeline on a Mastodon server.
+type MastodonTimeline struct {
+	// Server is the base address of the server, e.g. "https://mastodon.social".
+	Server string
+	// Account is the account's handle, possibly including a remote domain; empty for hashtag timelines.
+	Account string
+	// Tag is the hashtag without "#"; empty for account timelines.
+	Tag string
+}
+
+// ParseMastodonURL parses the profile link of an account, e.g. "https://mastodon.social/@user",
+// or of a hashtag, e.g. "https://mastodon.social/tags/news".
+func ParseMastodonURL(raw string) (MastodonTimeline, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return MastodonTimeline{}, fmt.Errorf("invalid Mastodon link %q", raw)
+	}
+	timeline := MastodonTimeline{Server: u.Scheme + "://" + u.Host}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && strings.HasPrefix(parts[0], "@") && len(parts[0]) > 1:
+		timeline.Account = parts[0][1:]
+	case len(parts) == 2 && parts[0] == "tags" && parts[1] != "":
+		timeline.Tag = parts[1]
+	default:
+		return MastodonTimeline{}, fmt.Errorf("link %q is neither a Mastodon account nor a hashtag", raw)
+	}
+	return timeline, nil
+}
+
+// MastodonFetcher reads an account's or a hashtag's public timeline through the Mastodon API.
+type MastodonFetcher struct {
+	// URL is the profile link of the account or hashtag, see ParseMastodonURL.
+	URL         string
+	SourceName  string
+	MaxBodySize int64
+	HTTP        *HTTPOptions
+}
+
+// Fetch fetches the timeline's statuses published after since.
+func (f *MastodonFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
+	timeline, err := ParseMastodonURL(f.URL)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Retry(attempts, delay, func() ([]NewsItem, error) {
+		return f.performFetch(timeline, since)
+	})
+}
+
+// performFetch performs the actual fetching and parsing logic.
+func (f *MastodonFetcher) performFetch(timeline MastodonTimeline, since time.Time) ([]NewsItem, error) {
+	apiURL := fmt.Sprintf("%s/api/v1/timelines/tag/%s?limit=%d", timeline.Server, url.PathEscape(timeline.Tag), mastodonPageSize)
+	feedTitle := "#" + timeline.Tag
+	if timeline.Account != "" {
+		accountID, err := f.lookupAccount(timeline)
+		if err != nil {
+			return nil, err
+		}
+		apiURL = fmt.Sprintf("%s/api/v1/accounts/%s/statuses?exclude_replies=true&limit=%d", timeline.Server, url.PathEscape(accountID), mastodonPageSize)
+		feedTitle = "@" + timeline.Account
+	}
+
+	body, err := f.get(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Fetching news", "source", f.SourceName, "timeline", feedTitle)
+	return ParseMastodonStatuses(bytes.NewReader(body), since, f.SourceName, feedTitle)
+}
+
+// lookupAccount resolves an account's handle to the ID the API addresses it by.
+func (f *MastodonFetcher) lookupAccount(timeline MastodonTimeline) (string, error) {
+	body, err := f.get(timeline.Server + "/api/v1/accounts/lookup?acct=" + url.QueryEscape(timeline.Account))
+	if err != nil {
+		return "", err
+	}
+	var account struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &account); err != nil {
+		return "", fmt.Errorf("error parsing account: %w", err)
+	}
+	if account.ID == "" {
+		return "", fmt.Errorf("account %s not found", timeline.Account)
+	}
+	return account.ID, nil
+}
+
+// get performs a GET request to the API and returns the response body.
+func (f *MastodonFetcher) get(apiURL string) ([]byte, error) {
+	client, req, err := createHTTPRequest(apiURL, "application/json", f.HTTP)
+	if err != nil {
+		return nil, err
+	}
+	defer client.CloseIdleConnections()
+	req = skipRobots(req)
+	body, _, err := getDocument(client, req, f.MaxBodySize, "")
+	return body, err
+}
+
+// ParseMastodonStatuses extracts the statuses published after since from a timeline API
+// response. Boosted statuses are reported with the boosted content. Replies to other accounts are
+// left out, as hashtag timelines include them; a thread's continuations are kept.
+func ParseMastodonStatuses(r io.Reader, since time.Time, sourceName, feedTitle string) ([]NewsItem, error) {
+	var statuses []mastodonStatus
+	if err := json.NewDecoder(r).Decode(&statuses); err != nil {
+		return nil, fmt.Errorf("error parsing statuses: %w", err)
+	}
+
+	var newsItems []NewsItem
+	for _, status := range statuses {
+		if !status.CreatedAt.After(since) {
+			continue
+		}
+		post := status
+		if status.Reblog != nil {
+			post = *status.Reblog
+		}
+		if post.InReplyToAccountID != "" && post.InReplyToAccountID != post.Account.ID {
+			continue
+		}
+
+		content := htmlToText(post.Content)
+		if post.SpoilerText != "" {
+			content = strings.TrimSpace(post.SpoilerText + "\n\n" + content)
+		}
+
+		var media []Media
+		for _, attachment := range post.MediaAttachments {
+			switch attachment.Type {
+			case "image":
+				media = append(media, Media{URL: attachment.URL, Medium: "image"})
+			case "video", "gifv":
+				media = append(media, Media{URL: attachment.URL, Medium: "video"})
+				if attachment.PreviewURL != "" {
+					media = append(media, Media{URL: attachment.PreviewURL, Medium: "image"})
+				}
+			}
+		}
+		if post.Card != nil && post.Card.Image != "" {
+			media = append(media, Media{URL: post.Card.Image, Medium: "image"})
+		}
+		if content == "" && len(media) == 0 {
+			continue
+		}
+
+		item := NewsItem{
+			GUID:        post.URI,
+			Title:       firstLineTitle(content, feedTitle),
+			Link:        post.URL,
+			Content:     content,
+			RawContent:  post.Content,
+			PublishedOn: status.CreatedAt,
+			Media:       media,
+			Language:    post.Language,
+			SourceName:  sourceName,
+			FeedTitle:   feedTitle,
+		}
+		if item.Link == "" {
+			item.Link = post.URI
+		}
+		for _, tag := range post.Tags {
+			item.Categories = append(item.Categories, tag.Name)
+		}
+		if author := post.Account.DisplayName; author != "" {
+			item.Authors = []string{author}
+		} else if post.Account.Acct != "" {
+			item.Authors = []string{"@" + post.Account.Acct}
+		}
+		for _, m := range media {
+			if m.Medium == "image" {
+				item.ImageURL = m.URL
+				break
+			}
+		}
+		newsItems = append(newsItems, item)
+	}
+	return newsItems, nil
+}
@@ -0,0 +1,175 @@
+package fetcher
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// parseMastodonFixture parses a recorded timeline API response from testdata.
+func parseMastodonFixture(t *testing.T, name, feedTitle string, since time.Time) []NewsItem {
+	t.Helper()
+	file, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	items, err := ParseMastodonStatuses(file, since, "NoNoise", feedTitle)
+	if err != nil {
+		t.Fatalf("ParseMastodonStatuses: %v", err)
+	}
+	return items
+}
+
+func TestParseMastodonStatusesAccount(t *testing.T) {
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	items := parseMastodonFixture(t, "mastodon_account_statuses.json", "@nonoise", since)
+
+	want := []NewsItem{
+		{
+			// A reply continuing the account's own thread is kept
+			GUID:        "https://mastodon.example/users/nonoise/statuses/113300000000000005",
+			Title:       "Update: the bridge reopens on Monday.",
+			Link:        "https://mastodon.example/@nonoise/113300000000000005",
+			Content:     "Update: the bridge reopens on Monday.",
+			PublishedOn: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+			Authors:     []string{"NoNoise"},
+			Language:    "en",
+			SourceName:  "NoNoise",
+			FeedTitle:   "@nonoise",
+		},
+		{
+			// The spoiler text leads the content; the link card's picture is the image
+			GUID:        "https://mastodon.example/users/nonoise/statuses/113300000000000004",
+			Title:       "Graphic images",
+			Link:        "https://mastodon.example/@nonoise/113300000000000004",
+			Content:     "Graphic images\n\nBridge collapse in the north.\nRescue teams are on site.\n\nhttps://news.example/bridge",
+			PublishedOn: time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC),
+			ImageURL:    "https://files.mastodon.example/cache/preview_cards/images/bridge.jpg",
+			Media:       []Media{{URL: "https://files.mastodon.example/cache/preview_cards/images/bridge.jpg", Medium: "image"}},
+			Authors:     []string{"NoNoise"},
+			Language:    "en",
+			SourceName:  "NoNoise",
+			FeedTitle:   "@nonoise",
+		},
+		{
+			// A boost carries the boosted status, published when it was boosted; the video's
+			// preview is the image
+			GUID:        "https://news.example/users/desk/statuses/113299000000000042",
+			Title:       "Rakete startet am Freitag",
+			Link:        "https://news.example/@desk/113299000000000042",
+			Content:     "Rakete startet am Freitag",
+			PublishedOn: time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC),
+			ImageURL:    "https://files.mastodon.example/cache/media_attachments/files/launch.png",
+			Media: []Media{
+				{URL: "https://files.mastodon.example/cache/media_attachments/files/launch.mp4", Medium: "video"},
+				{URL: "https://files.mastodon.example/cache/media_attachments/files/launch.png", Medium: "image"},
+			},
+			Categories: []string{"raumfahrt"},
+			Authors:    []string{"@desk@news.example"},
+			Language:   "de",
+			SourceName: "NoNoise",
+			FeedTitle:  "@nonoise",
+		},
+		{
+			GUID:        "https://mastodon.example/users/nonoise/statuses/113300000000000002",
+			Title:       "Election results are in #elections",
+			Link:        "https://mastodon.example/@nonoise/113300000000000002",
+			Content:     "Election results are in #elections",
+			PublishedOn: time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+			ImageURL:    "https://files.mastodon.example/media_attachments/files/results.jpg",
+			Media: []Media{
+				{URL: "https://files.mastodon.example/media_attachments/files/results.jpg", Medium: "image"},
+				{URL: "https://files.mastodon.example/media_attachments/files/turnout.jpg", Medium: "image"},
+			},
+			Categories: []string{"elections"},
+			Authors:    []string{"NoNoise"},
+			Language:   "en",
+			SourceName: "NoNoise",
+			FeedTitle:  "@nonoise",
+		},
+	}
+	compareItems(t, items, want)
+}
+
+func TestParseMastodonStatusesTag(t *testing.T) {
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	items := parseMastodonFixture(t, "mastodon_tag_timeline.json", "#climate", since)
+
+	// The reply of another account to the thread is left out
+	want := []NewsItem{
+		{
+			GUID:        "https://mastodon.example/users/reporter/statuses/113300000000000102",
+			Title:       "2/ Sea ice is at a record low for October. #climate",
+			Link:        "https://mastodon.example/@reporter/113300000000000102",
+			Content:     "2/ Sea ice is at a record low for October. #climate",
+			PublishedOn: time.Date(2026, 10, 17, 9, 15, 0, 0, time.UTC),
+			ImageURL:    "https://files.mastodon.example/media_attachments/files/small/ice.png",
+			Media: []Media{
+				{URL: "https://files.mastodon.example/media_attachments/files/ice.mp4", Medium: "video"},
+				{URL: "https://files.mastodon.example/media_attachments/files/small/ice.png", Medium: "image"},
+			},
+			Categories: []string{"climate"},
+			Authors:    []string{"Climate Reporter"},
+			Language:   "en",
+			SourceName: "NoNoise",
+			FeedTitle:  "#climate",
+		},
+		{
+			GUID:        "https://mastodon.example/users/reporter/statuses/113300000000000101",
+			Title:       "1/ This summer was the hottest on record. #climate #heat",
+			Link:        "https://mastodon.example/@reporter/113300000000000101",
+			Content:     "1/ This summer was the hottest on record. #climate #heat",
+			PublishedOn: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+			Categories:  []string{"climate", "heat"},
+			Authors:     []string{"Climate Reporter"},
+			Language:    "en",
+			SourceName:  "NoNoise",
+			FeedTitle:   "#climate",
+		},
+	}
+	compareItems(t, items, want)
+}
+
+func TestParseMastodonStatusesSince(t *testing.T) {
+	tests := []struct {
+		name  string
+		since time.Time
+		want  int
+	}{
+		{"all", time.Time{}, 5},
+		{"cutoff at the boost", time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC), 2},
+		// The boosted status is older, but the boost is within the window
+		{"cutoff after the boosted status", time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), 3},
+		{"after the last status", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := parseMastodonFixture(t, "mastodon_account_statuses.json", "@nonoise", tt.since)
+			if len(items) != tt.want {
+				t.Errorf("got %d items, want %d", len(items), tt.want)
+			}
+			for _, item := range items {
+				if !item.PublishedOn.After(tt.since) {
+					t.Errorf("item %s published %v, not after %v", item.GUID, item.PublishedOn, tt.since)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMastodonStatusesRawContent(t *testing.T) {
+	items := parseMastodonFixture(t, "mastodon_tag_timeline.json", "#climate", time.Time{})
+	var raw []string
+	for _, item := range items {
+		raw = append(raw, item.RawContent)
+	}
+	want := []string{
+		`<p>2/ Sea ice is at a record low for October. <a href="https://mastodon.example/tags/climate" class="mention hashtag" rel="tag">#<span>climate</span></a></p>`,
+		`<p>1/ This summer was the hottest on record. <a href="https://mastodon.example/tags/climate" class="mention hashtag" rel="tag">#<span>climate</span></a> <a href="https://mastodon.example/tags/heat" class="mention hashtag" rel="tag">#<span>heat</span></a></p>`,
+	}
+	if !reflect.DeepEqual(raw, want) {
+		t.Errorf("RawContent = %q, want %q", raw, want)
+	}
+}
@@ -0,0 +1,304 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"news/store"
+)
+
+// DefaultRobotsTTL is how long a fetched robots.txt file is used before it is fetched again.
+const DefaultRobotsTTL = 24 * time.Hour
+
+// DefaultRobotsAgent is the product token robots.txt rules are looked up for by default.
+const DefaultRobotsAgent = "NoNoiseBot"
+
+// politeness is the limiter all requests of the fetchers go through; nil imposes no limits.
+var politeness *Politeness
+
+// SetPoliteness makes the requests of all fetchers go through p; nil turns the limits off.
+func SetPoliteness(p *Politeness) {
+	politeness = p
+}
+
+// PolitenessOptions are the limits a Politeness keeps to.
+type PolitenessOptions struct {
+	// Interval is the least time between two requests to a host.
+	Interval time.Duration
+	// MaxCrawlDelay caps the Crawl-delay of robots.txt files, so no site can stall a run.
+	MaxCrawlDelay time.Duration
+	// MaxConcurrency is the largest number of requests in flight to a host; unlimited if not positive.
+	MaxConcurrency int
+	// RespectRobots turns on the robots.txt checks.
+	RespectRobots bool
+	// RobotsCacheFile keeps the fetched robots.txt files between runs; they are kept in memory only if empty.
+	RobotsCacheFile string
+	// RobotsTTL is how long a fetched robots.txt file is used; DefaultRobotsTTL if not positive.
+	RobotsTTL time.Duration
+	// RobotsAgent is the product token robots.txt rules are looked up for when the User-Agent
+	// poses as a browser; DefaultRobotsAgent if empty.
+	RobotsAgent string
+}
+
+// Politeness spaces out and limits the requests to each host, and checks them against the host's
+// robots.txt, so the fetchers neither overload publishers nor get banned by them.
+type Politeness struct {
+	options PolitenessOptions
+	cache   *store.JSONFile[map[string]robotsEntry]
+
+	mu     sync.Mutex
+	hosts  map[string]*hostLimit
+	robots map[string]robotsEntry
+}
+
+// hostLimit is the limiter and request slots of one host.
+type hostLimit struct {
+	limiter *rate.Limiter
+	slots   chan struct{}
+	// robotsMu makes concurrent requests wait for one fetch of the host's robots.txt.
+	robotsMu sync.Mutex
+}
+
+// robotsEntry is a fetched robots.txt file. Body is empty for hosts without one.
+type robotsEntry struct {
+	Body      string    `json:"body,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// RobotsError reports a request robots.txt disallows.
+type RobotsError struct {
+	URL string
+}
+
+func (e *RobotsError) Error() string {
+	return fmt.Sprintf("robots.txt disallows fetching %s", e.URL)
+}
+
+// NewPoliteness creates a Politeness keeping to the options.
+func NewPoliteness(options PolitenessOptions) *Politeness {
+	if options.RobotsTTL <= 0 {
+		options.RobotsTTL = DefaultRobotsTTL
+	}
+	if options.RobotsAgent == "" {
+		options.RobotsAgent = DefaultRobotsAgent
+	}
+	p := &Politeness{options: options, hosts: make(map[string]*hostLimit)}
+	if options.RobotsCacheFile != "" {
+		p.cache = store.NewJSONFile[map[string]robotsEntry](options.RobotsCacheFile)
+	}
+	return p
+}
+
+// skipRobotsKey marks requests that are not checked against robots.txt.
+type skipRobotsKey struct{}
+
+// skipRobots exempts a request from the robots.txt check, for official APIs, which are meant for
+// programs, and sources whose publishers allow the bot. Pages and listings published for
+// browsers, such as Reddit's, are not APIs and stay checked. It is still rate limited.
+func skipRobots(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), skipRobotsKey{}, true))
+}
+
+// acquire waits until the request may be sent to its host and returns the function releasing its
+// slot once the response is read. It fails if robots.txt disallows the request.
+func (p *Politeness) acquire(client *http.Client, req *http.Request) (func(), error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	origin := originOf(req.URL)
+	host := p.host(origin)
+
+	release := func() {}
+	if host.slots != nil {
+		select {
+		case host.slots <- struct{}{}:
+			release = func() { <-host.slots }
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	if p.options.RespectRobots && req.Context().Value(skipRobotsKey{}) == nil {
+		rules, err := p.robotsRules(client, req, origin, host)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		if !rules.Allowed(req.URL.RequestURI()) {
+			release()
+			return nil, &RobotsError{URL: req.URL.String()}
+		}
+		p.setCrawlDelay(host, rules.CrawlDelay)
+	}
+
+	if err := host.limiter.Wait(req.Context()); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// host returns the limits of a host, given as scheme and host, creating them on first use.
+func (p *Politeness) host(origin string) *hostLimit {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	host, ok := p.hosts[origin]
+	if !ok {
+		host = &hostLimit{limiter: rate.NewLimiter(interval(p.options.Interval), 1)}
+		if p.options.MaxConcurrency > 0 {
+			host.slots = make(chan struct{}, p.options.MaxConcurrency)
+		}
+		p.hosts[origin] = host
+	}
+	return host
+}
+
+// setCrawlDelay slows the requests to a host down to its crawl delay, up to MaxCrawlDelay.
+func (p *Politeness) setCrawlDelay(host *hostLimit, crawlDelay time.Duration) {
+	if p.options.MaxCrawlDelay > 0 && crawlDelay > p.options.MaxCrawlDelay {
+		crawlDelay = p.options.MaxCrawlDelay
+	}
+	if crawlDelay < p.options.Interval {
+		crawlDelay = p.options.Interval
+	}
+	if limit := interval(crawlDelay); host.limiter.Limit() != limit {
+		host.limiter.SetLimit(limit)
+	}
+}
+
+// interval returns the rate of one request per d; no limit if d is not positive.
+func interval(d time.Duration) rate.Limit {
+	if d <= 0 {
+		return rate.Inf
+	}
+	return rate.Every(d)
+}
+
+// robotsRules returns the host's robots.txt rules for the request's user agent, fetching the file
+// if it is not cached or has expired.
+func (p *Politeness) robotsRules(client *http.Client, req *http.Request, origin string, host *hostLimit) (*RobotsRules, error) {
+	host.robotsMu.Lock()
+	defer host.robotsMu.Unlock()
+
+	agent := p.robotsAgent(req.UserAgent())
+	entry, ok := p.cachedRobots(origin)
+	if ok && time.Since(entry.FetchedAt) < p.options.RobotsTTL {
+		return ParseRobots(strings.NewReader(entry.Body), agent), nil
+	}
+
+	if err := host.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	fetched, err := fetchRobots(client, req, origin)
+	if err != nil {
+		if ok {
+			// An expired file is better than none while the host's robots.txt is unavailable
+			log.Printf("Using expired robots.txt of %s: %v", origin, err)
+			return ParseRobots(strings.NewReader(entry.Body), agent), nil
+		}
+		return nil, err
+	}
+	p.cacheRobots(origin, fetched)
+	return ParseRobots(strings.NewReader(fetched.Body), agent), nil
+}
+
+// fetchRobots fetches the robots.txt file of a host with the request's headers. Hosts answering
+// with a client error have no rules; server errors make the host unavailable until they pass.
+func fetchRobots(client *http.Client, req *http.Request, origin string) (robotsEntry, error) {
+	robotsReq, err := http.NewRequestWithContext(req.Context(), "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return robotsEntry{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	robotsReq.Header = req.Header.Clone()
+	robotsReq.Header.Set("Accept", "text/plain")
+
+	resp, err := client.Do(robotsReq)
+	if err != nil {
+		return robotsEntry{}, fmt.Errorf("error fetching %s: %w", robotsReq.URL, err)
+	}
+	defer resp.Body.Close()
+
+	entry := robotsEntry{FetchedAt: time.Now()}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		body, err := readBody(resp, DefaultMaxBodySize)
+		if err != nil {
+			return robotsEntry{}, err
+		}
+		entry.Body = string(bytes.ToValidUTF8(body, nil))
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return robotsEntry{}, fmt.Errorf("robots.txt of %s is unavailable: %w", origin,
+			&StatusError{URL: robotsReq.URL.String(), StatusCode: resp.StatusCode})
+	}
+	return entry, nil
+}
+
+// cachedRobots returns the cached robots.txt file of a host, loading the cache file on first use.
+func (p *Politeness) cachedRobots(origin string) (robotsEntry, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.robots == nil {
+		p.robots = make(map[string]robotsEntry)
+		if p.cache != nil {
+			cached, err := p.cache.Load()
+			if err != nil {
+				log.Printf("Failed to load robots.txt cache: %v", err)
+			}
+			for key, entry := range cached {
+				p.robots[key] = entry
+			}
+		}
+	}
+	entry, ok := p.robots[origin]
+	return entry, ok
+}
+
+// cacheRobots stores the robots.txt file of a host in memory and in the cache file.
+func (p *Politeness) cacheRobots(origin string, entry robotsEntry) {
+	p.mu.Lock()
+	p.robots[origin] = entry
+	p.mu.Unlock()
+	if p.cache == nil {
+		return
+	}
+	err := p.cache.Update(func(cached *map[string]robotsEntry) error {
+		if *cached == nil {
+			*cached = make(map[string]robotsEntry)
+		}
+		for key, entry := range *cached {
+			if time.Since(entry.FetchedAt) >= p.options.RobotsTTL {
+				delete(*cached, key)
+			}
+		}
+		(*cached)[origin] = entry
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to save robots.txt cache: %v", err)
+	}
+}
+
+// robotsAgent returns the product token by which robots.txt groups are matched: that of the
+// User-Agent, e.g. "NewsBot" for "NewsBot/1.0 (+https://example.com/bot)", or RobotsAgent for
+// browser User-Agents, whose "Mozilla" token would only match the "*" groups.
+func (p *Politeness) robotsAgent(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), "/")
+	token, _, _ = strings.Cut(token, " ")
+	if token == "" || strings.EqualFold(token, "Mozilla") {
+		return p.options.RobotsAgent
+	}
+	return token
+}
+
+// originOf returns the scheme and host of a link, by which hosts are told apart.
+func originOf(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,165 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"news/utils"
+)
+
+// redditPageSize is the number of posts requested, the most a listing returns at once.
+const redditPageSize = 100
+
+// redditListing is the subset of a subreddit listing the fetcher uses.
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Kind string `json:"kind"`
+			Data struct {
+				Name          string  `json:"name"`
+				Title         string  `json:"title"`
+				URL           string  `json:"url"`
+				Permalink     string  `json:"permalink"`
+				Selftext      string  `json:"selftext"`
+				SelftextHTML  string  `json:"selftext_html"`
+				CreatedUTC    float64 `json:"created_utc"`
+				Score         int     `json:"score"`
+				NumComments   int     `json:"num_comments"`
+				Author        string  `json:"author"`
+				Subreddit     string  `json:"subreddit_name_prefixed"`
+				LinkFlairText string  `json:"link_flair_text"`
+				IsSelf        bool    `json:"is_self"`
+				Stickied      bool    `json:"stickied"`
+				Over18        bool    `json:"over_18"`
+				Preview       *struct {
+					Images []struct {
+						Source struct {
+							URL string `json:"url"`
+						} `json:"source"`
+					} `json:"images"`
+				} `json:"preview"`
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// RedditListingURL returns the JSON listing address of a subreddit given as "r/name" or a link,
+// e.g. "https://www.reddit.com/r/technology/top/?t=day". Links without a sort order list hot posts.
+func RedditListingURL(subreddit string) (string, error) {
+	raw := strings.TrimSpace(subreddit)
+	if strings.HasPrefix(raw, "r/") || strings.HasPrefix(raw, "/r/") {
+		raw = "https://www.reddit.com/" + strings.TrimPrefix(raw, "/")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid subreddit %q", subreddit)
+	}
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(u.Path, ".json"), "/"), "/")
+	if len(parts) < 2 || parts[0] != "r" || parts[1] == "" {
+		return "", fmt.Errorf("no subreddit in link %q", subreddit)
+	}
+	sort := "hot"
+	if len(parts) > 2 {
+		sort = parts[2]
+	}
+	switch sort {
+	case "hot", "new", "top", "rising", "best", "controversial":
+	default:
+		return "", fmt.Errorf("unknown sort order %q in link %q", sort, subreddit)
+	}
+
+	query := u.Query()
+	query.Set("limit", fmt.Sprint(redditPageSize))
+	query.Set("raw_json", "1")
+	listing := url.URL{Scheme: "https", Host: "www.reddit.com", Path: "/r/" + parts[1] + "/" + sort + ".json", RawQuery: query.Encode()}
+	return listing.String(), nil
+}
+
+// RedditFetcher reads the posts of a subreddit from its JSON listing, with their scores and
+// comment counts.
+type RedditFetcher struct {
+	// Subreddit is the subreddit, see RedditListingURL.
+	Subreddit   string
+	SourceName  string
+	MaxBodySize int64
+	HTTP        *HTTPOptions
+}
+
+// Fetch fetches the subreddit's posts published after since.
+func (f *RedditFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
+	listingURL, err := RedditListingURL(f.Subreddit)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Retry(attempts, delay, func() ([]NewsItem, error) {
+		client, req, err := createHTTPRequest(listingURL, "application/json", f.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		defer client.CloseIdleConnections()
+		body, _, err := getDocument(client, req, f.MaxBodySize, "")
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Fetching news", "source", f.SourceName, "listing", listingURL)
+		return ParseRedditListing(bytes.NewReader(body), since, f.SourceName)
+	})
+}
+
+// ParseRedditListing extracts the posts published after since from a subreddit listing.
+// Pinned posts, usually moderator announcements, and posts marked NSFW are left out.
+func ParseRedditListing(r io.Reader, since time.Time, sourceName string) ([]NewsItem, error) {
+	var listing redditListing
+	if err := json.NewDecoder(r).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("error parsing listing: %w", err)
+	}
+
+	var newsItems []NewsItem
+	for _, child := range listing.Data.Children {
+		post := child.Data
+		if child.Kind != "t3" || post.Stickied || post.Over18 {
+			continue
+		}
+		publishedOn := time.Unix(int64(post.CreatedUTC), 0)
+		if !publishedOn.After(since) {
+			continue
+		}
+
+		commentsURL := "https://www.reddit.com" + post.Permalink
+		item := NewsItem{
+			GUID:        post.Name,
+			Title:       post.Title,
+			Link:        post.URL,
+			Content:     post.Selftext,
+			RawContent:  post.SelftextHTML,
+			PublishedOn: publishedOn,
+			SourceName:  sourceName,
+			FeedTitle:   post.Subreddit,
+			Score:       post.Score,
+			Comments:    post.NumComments,
+			CommentsURL: commentsURL,
+		}
+		if post.IsSelf || item.Link == "" {
+			item.Link = commentsURL
+		}
+		if post.Author != "" && post.Author != "[deleted]" {
+			item.Authors = []string{"u/" + post.Author}
+		}
+		if post.LinkFlairText != "" {
+			item.Categories = []string{post.LinkFlairText}
+		}
+		if post.Preview != nil && len(post.Preview.Images) > 0 {
+			item.ImageURL = html.UnescapeString(post.Preview.Images[0].Source.URL)
+			item.Media = []Media{{URL: item.ImageURL, Medium: "image"}}
+		}
+		newsItems = append(newsItems, item)
+	}
+	return newsItems, nil
+}
@@ -0,0 +1,137 @@
+package fetcher
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// parseRedditFixture parses a recorded subreddit listing from testdata.
+func parseRedditFixture(t *testing.T, since time.Time) []NewsItem {
+	t.Helper()
+	file, err := os.Open("testdata/reddit_listing.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	items, err := ParseRedditListing(file, since, "RTech")
+	if err != nil {
+		t.Fatalf("ParseRedditListing: %v", err)
+	}
+	return items
+}
+
+func TestParseRedditListing(t *testing.T) {
+	since := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	items := parseRedditFixture(t, since)
+
+	// The pinned and the NSFW posts are left out, and so is the post older than since
+	want := []NewsItem{
+		{
+			// A link post links to the article, its preview being the image
+			GUID:        "t3_1g5chip",
+			Title:       "Chipmaker unveils a 2nm processor for laptops",
+			Link:        "https://news.example/chips/2nm",
+			PublishedOn: time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC),
+			ImageURL:    "https://external-preview.redd.it/chip.jpg?width=1200&format=pjpg&s=abc",
+			Media:       []Media{{URL: "https://external-preview.redd.it/chip.jpg?width=1200&format=pjpg&s=abc", Medium: "image"}},
+			Categories:  []string{"Hardware"},
+			Authors:     []string{"u/silicon_watcher"},
+			SourceName:  "RTech",
+			FeedTitle:   "r/technology",
+			Score:       1520,
+			Comments:    230,
+			CommentsURL: "https://www.reddit.com/r/technology/comments/1g5chip/chipmaker_unveils_a_2nm_processor/",
+		},
+		{
+			// A text post links to its discussion
+			GUID:        "t3_1g5ask",
+			Title:       "Which laptop lasts longest on battery?",
+			Link:        "https://www.reddit.com/r/technology/comments/1g5ask/which_laptop_lasts_longest/",
+			Content:     "Looking for real-world numbers, not the spec sheet.",
+			PublishedOn: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+			Authors:     []string{"u/road_warrior"},
+			SourceName:  "RTech",
+			FeedTitle:   "r/technology",
+			Score:       310,
+			Comments:    145,
+			CommentsURL: "https://www.reddit.com/r/technology/comments/1g5ask/which_laptop_lasts_longest/",
+		},
+		{
+			// A deleted account is no author
+			GUID:        "t3_1g5gone",
+			Title:       "Browser drops support for an old protocol",
+			Link:        "https://news.example/browsers/protocol",
+			PublishedOn: time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+			SourceName:  "RTech",
+			FeedTitle:   "r/technology",
+			Score:       75,
+			Comments:    9,
+			CommentsURL: "https://www.reddit.com/r/technology/comments/1g5gone/browser_drops_support/",
+		},
+	}
+	compareItems(t, items, want)
+
+	if raw := items[1].RawContent; raw != `<div class="md"><p>Looking for real-world numbers, not the spec sheet.</p></div>` {
+		t.Errorf("RawContent = %q, want the post's HTML", raw)
+	}
+}
+
+func TestParseRedditListingSince(t *testing.T) {
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []string
+	}{
+		{"all", time.Time{}, []string{"t3_1g5chip", "t3_1g5ask", "t3_1g5gone", "t3_1g5old"}},
+		{"cutoff at a post", time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), []string{"t3_1g5chip"}},
+		{"after the last post", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var guids []string
+			for _, item := range parseRedditFixture(t, tt.since) {
+				guids = append(guids, item.GUID)
+			}
+			if !reflect.DeepEqual(guids, tt.want) {
+				t.Errorf("GUIDs = %q, want %q", guids, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedditListingURL(t *testing.T) {
+	tests := []struct {
+		subreddit string
+		want      string
+		wantErr   bool
+	}{
+		{subreddit: "r/technology", want: "https://www.reddit.com/r/technology/hot.json?limit=100&raw_json=1"},
+		{subreddit: "/r/technology", want: "https://www.reddit.com/r/technology/hot.json?limit=100&raw_json=1"},
+		{subreddit: "https://www.reddit.com/r/technology/", want: "https://www.reddit.com/r/technology/hot.json?limit=100&raw_json=1"},
+		{subreddit: "https://www.reddit.com/r/technology/top/?t=day", want: "https://www.reddit.com/r/technology/top.json?limit=100&raw_json=1&t=day"},
+		{subreddit: "https://old.reddit.com/r/worldnews/new.json", want: "https://www.reddit.com/r/worldnews/new.json?limit=100&raw_json=1"},
+		{subreddit: "https://www.reddit.com/user/someone", wantErr: true},
+		{subreddit: "r/", wantErr: true},
+		{subreddit: "r/technology/sideways", wantErr: true},
+		{subreddit: "technology", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subreddit, func(t *testing.T) {
+			got, err := RedditListingURL(tt.subreddit)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("RedditListingURL(%q) = %q, want an error", tt.subreddit, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RedditListingURL(%q): %v", tt.subreddit, err)
+			}
+			if got != tt.want {
+				t.Errorf("RedditListingURL(%q) = %q, want %q", tt.subreddit, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package fetcher
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsMaxSize is the part of a robots.txt file that is read, as RFC 9309 requires at least.
+const robotsMaxSize = 500 << 10
+
+// RobotsRules are the rules of a robots.txt file that apply to one user agent.
+type RobotsRules struct {
+	rules []robotsRule
+	// CrawlDelay is the time the site asks to leave between requests; zero if it does not say.
+	CrawlDelay time.Duration
+}
+
+// robotsRule allows or disallows the paths its pattern matches.
+type robotsRule struct {
+	pattern *regexp.Regexp
+	// length is the length of the pattern as written, by which the most specific rule is chosen.
+	length int
+	allow  bool
+}
+
+// robotsGroup is a group of rules and the user agents it addresses.
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// ParseRobots reads the rules of a robots.txt file for the user agent whose product token is
+// agent, e.g. "NoNoiseBot". The groups naming the agent apply, or the "*" groups if none does.
+// Lines that are not understood are ignored, as the standard asks.
+func ParseRobots(r io.Reader, agent string) *RobotsRules {
+	var groups []*robotsGroup
+	var group *robotsGroup
+	inAgents := false
+	scanner := bufio.NewScanner(io.LimitReader(r, robotsMaxSize))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			// Consecutive user-agent lines share a group
+			if !inAgents {
+				group = &robotsGroup{}
+				groups = append(groups, group)
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		}
+		inAgents = false
+		if group == nil {
+			continue
+		}
+		switch key {
+		case "allow", "disallow":
+			if value == "" {
+				continue
+			}
+			group.rules = append(group.rules, robotsRule{pattern: robotsPattern(value), length: len(value), allow: key == "allow"})
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				group.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	rules := matchRobotsGroups(groups, func(name string) bool { return agent != "" && name == agent })
+	if rules == nil {
+		rules = matchRobotsGroups(groups, func(name string) bool { return name == "*" })
+	}
+	if rules == nil {
+		rules = &RobotsRules{}
+	}
+	return rules
+}
+
+// matchRobotsGroups merges the groups one of whose agents matches, or returns nil if none does.
+func matchRobotsGroups(groups []*robotsGroup, match func(string) bool) *RobotsRules {
+	var rules *RobotsRules
+	for _, group := range groups {
+		if !slices.ContainsFunc(group.agents, match) {
+			continue
+		}
+		if rules == nil {
+			rules = &RobotsRules{}
+		}
+		rules.rules = append(rules.rules, group.rules...)
+		if group.crawlDelay > rules.CrawlDelay {
+			rules.CrawlDelay = group.crawlDelay
+		}
+	}
+	return rules
+}
+
+// robotsPattern compiles a path pattern, in which "*" matches any characters and a final "$"
+// anchors the end of the path.
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// Allowed reports whether the path, with its query, may be fetched. The longest matching rule
+// decides, an allow rule winning a tie; paths no rule matches are allowed.
+func (r *RobotsRules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	allowed, length := true, -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > length || rule.length == length && rule.allow {
+			allowed, length = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
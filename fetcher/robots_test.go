@@ -0,0 +1,229 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobotsAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		robots string
+		agent  string
+		paths  map[string]bool
+	}{
+		{
+			name:   "no rules",
+			robots: "",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/": true, "/news/1": true},
+		},
+		{
+			name:   "prefix match",
+			robots: "User-agent: *\nDisallow: /private\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/private": false, "/private/page": false, "/privateer": false, "/public": true, "/": true},
+		},
+		{
+			name:   "empty disallow allows everything",
+			robots: "User-agent: *\nDisallow:\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/": true, "/anything": true},
+		},
+		{
+			name:   "dollar anchors the end",
+			robots: "User-agent: *\nDisallow: /*.pdf$\nDisallow: /exact$\n",
+			agent:  "NoNoiseBot",
+			paths: map[string]bool{
+				"/files/report.pdf":     false,
+				"/files/report.pdf?x=1": true,
+				"/files/report.pdfx":    true,
+				"/exact":                false,
+				"/exact/":               true,
+				"/exactly":              true,
+			},
+		},
+		{
+			name:   "dollar inside a pattern is literal",
+			robots: "User-agent: *\nDisallow: /price$list\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/price$list": false, "/price": true},
+		},
+		{
+			name:   "wildcards",
+			robots: "User-agent: *\nDisallow: /*/print\nDisallow: /*?session=\n",
+			agent:  "NoNoiseBot",
+			paths: map[string]bool{
+				"/news/1/print":     false,
+				"/print":            true,
+				"/news?session=abc": false,
+				"/news?page=2":      true,
+			},
+		},
+		{
+			name:   "regexp characters are literal",
+			robots: "User-agent: *\nDisallow: /a.b+(c)\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/a.b+(c)": false, "/axb+(c)": true, "/a.bb(c)": true},
+		},
+		{
+			name:   "longest match wins",
+			robots: "User-agent: *\nDisallow: /news\nAllow: /news/public\nDisallow: /news/public/drafts\n",
+			agent:  "NoNoiseBot",
+			paths: map[string]bool{
+				"/news/1":                 false,
+				"/news/public/1":          true,
+				"/news/public/drafts/1":   false,
+				"/newsletter":             false,
+				"/news/publication/index": true,
+			},
+		},
+		{
+			name:   "allow wins a tie",
+			robots: "User-agent: *\nDisallow: /page\nAllow: /page\nDisallow: /*.html\nAllow: /a/b.ht\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/page": true, "/page/2": true, "/a/b.html": true, "/c/d.html": false},
+		},
+		{
+			name:   "disallow written first still loses a tie",
+			robots: "User-agent: *\nAllow: /page\nDisallow: /page\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/page": true},
+		},
+		{
+			name:   "robots.txt itself is always allowed",
+			robots: "User-agent: *\nDisallow: /\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/robots.txt": true, "/": false, "": false},
+		},
+		{
+			name:   "named group replaces the star group",
+			robots: "User-agent: *\nDisallow: /\n\nUser-agent: NoNoiseBot\nDisallow: /private\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/": true, "/news": true, "/private": false},
+		},
+		{
+			name:   "agent names match case-insensitively",
+			robots: "User-agent: nonoisebot\nDisallow: /private\n\nUser-agent: *\nDisallow: /\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/news": true, "/private": false},
+		},
+		{
+			name:   "other agents' groups do not apply",
+			robots: "User-agent: Googlebot\nDisallow: /\n\nUser-agent: *\nDisallow: /private\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/news": true, "/private": false},
+		},
+		{
+			name:   "star group without a named one",
+			robots: "User-agent: Googlebot\nDisallow: /\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/": true},
+		},
+		{
+			name:   "empty agent only matches the star group",
+			robots: "User-agent: NoNoiseBot\nDisallow: /\n\nUser-agent: *\nDisallow: /private\n",
+			agent:  "",
+			paths:  map[string]bool{"/news": true, "/private": false},
+		},
+		{
+			name:   "consecutive user-agent lines share a group",
+			robots: "User-agent: Googlebot\nUser-agent: NoNoiseBot\nDisallow: /shared\n\nUser-agent: *\nDisallow: /\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/shared": false, "/news": true},
+		},
+		{
+			name:   "groups naming the agent are merged",
+			robots: "User-agent: NoNoiseBot\nDisallow: /a\n\nUser-agent: *\nDisallow: /\n\nUser-agent: NoNoiseBot\nDisallow: /b\nAllow: /a/open\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/a": false, "/b": false, "/a/open": true, "/c": true},
+		},
+		{
+			name:   "star groups are merged",
+			robots: "User-agent: *\nDisallow: /a\n\nUser-agent: Googlebot\nDisallow: /\n\nUser-agent: *\nDisallow: /b\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/a": false, "/b": false, "/c": true},
+		},
+		{
+			name:   "comments, blank lines and unknown lines are ignored",
+			robots: "# Our rules\nSitemap: https://example.com/sitemap.xml\nUser-agent: * # everyone\n\nNoindex: /x\nDisallow: /private # not for bots\nnonsense\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/private": false, "/x": true},
+		},
+		{
+			name:   "rules before any user-agent are ignored",
+			robots: "Disallow: /\nUser-agent: *\nDisallow: /private\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/": true, "/private": false},
+		},
+		{
+			name:   "keys are case-insensitive",
+			robots: "USER-AGENT: *\nDISALLOW: /private\nallow: /private/ok\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/private": false, "/private/ok": true},
+		},
+		{
+			name:   "queries are matched",
+			robots: "User-agent: *\nDisallow: /search?\n",
+			agent:  "NoNoiseBot",
+			paths:  map[string]bool{"/search?q=news": false, "/search": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := ParseRobots(strings.NewReader(tt.robots), tt.agent)
+			for path, want := range tt.paths {
+				if got := rules.Allowed(path); got != want {
+					t.Errorf("Allowed(%q) = %v, want %v", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRobotsCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		robots string
+		want   time.Duration
+	}{
+		{"none", "User-agent: *\nDisallow: /private\n", 0},
+		{"seconds", "User-agent: *\nCrawl-delay: 5\n", 5 * time.Second},
+		{"fraction", "User-agent: *\nCrawl-delay: 0.5\n", 500 * time.Millisecond},
+		{"invalid", "User-agent: *\nCrawl-delay: soon\n", 0},
+		{"negative", "User-agent: *\nCrawl-delay: -3\n", 0},
+		{"named group", "User-agent: *\nCrawl-delay: 30\n\nUser-agent: NoNoiseBot\nCrawl-delay: 2\n", 2 * time.Second},
+		{"longest of merged groups", "User-agent: NoNoiseBot\nCrawl-delay: 2\n\nUser-agent: NoNoiseBot\nCrawl-delay: 7\n", 7 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseRobots(strings.NewReader(tt.robots), "NoNoiseBot").CrawlDelay; got != tt.want {
+				t.Errorf("CrawlDelay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolitenessRobotsAgent(t *testing.T) {
+	tests := []struct {
+		name        string
+		robotsAgent string
+		userAgent   string
+		want        string
+	}{
+		{"browser", "", DefaultUserAgent, DefaultRobotsAgent},
+		{"browser with a configured token", "NewsDesk", DefaultUserAgent, "NewsDesk"},
+		{"empty", "", "", DefaultRobotsAgent},
+		{"bot", "NewsDesk", "NewsBot/1.0 (+https://example.com/bot)", "NewsBot"},
+		{"bot without a version", "", "NewsBot", "NewsBot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPoliteness(PolitenessOptions{RobotsAgent: tt.robotsAgent})
+			if got := p.robotsAgent(tt.userAgent); got != tt.want {
+				t.Errorf("robotsAgent(%q) = %q, want %q", tt.userAgent, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"news/utils"
+)
+
+var backgroundImageRe = regexp.MustCompile(`background-image:\s*url\(['"]?([^'")]+)['"]?\)`)
+
+// TelegramChannelFetcher reads the posts of a public Telegram channel from its web preview at t.me/s/<channel>.
+type TelegramChannelFetcher struct {
+	// Channel is the channel's username, with or without "@", or a t.me link to it.
+	Channel     string
+	SourceName  string
+	MaxBodySize int64
+	HTTP        *HTTPOptions
+}
+
+// TelegramChannelURL returns the web preview address of a channel given as a username or t.me link.
+func TelegramChannelURL(channel string) (string, error) {
+	name := strings.TrimSpace(channel)
+	if strings.Contains(name, "t.me/") {
+		u, err := url.Parse(name)
+		if err != nil || u.Host == "" {
+			u, err = url.Parse("https://" + strings.TrimPrefix(strings.TrimPrefix(name, "http://"), "https://"))
+			if err != nil {
+				return "", fmt.Errorf("invalid channel link %q", channel)
+			}
+		}
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) > 0 && parts[0] == "s" {
+			parts = parts[1:]
+		}
+		if len(parts) == 0 {
+			return "", fmt.Errorf("no channel in link %q", channel)
+		}
+		name = parts[0]
+	}
+	name = strings.TrimPrefix(name, "@")
+	if name == "" || strings.ContainsAny(name, "/?# ") {
+		return "", fmt.Errorf("invalid channel %q", channel)
+	}
+	return "https://t.me/s/" + name, nil
+}
+
+// Fetch fetches the channel's latest posts published after since.
+func (f *TelegramChannelFetcher) Fetch(since time.Time, attempts int, delay time.Duration) ([]NewsItem, error) {
+	pageURL, err := TelegramChannelURL(f.Channel)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Retry(attempts, delay, func() ([]NewsItem, error) {
+		client, req, err := createHTTPRequest(pageURL, "", f.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		defer client.CloseIdleConnections()
+		return f.performFetch(client, req, since)
+	})
+}
+
+// performFetch performs the actual fetching and parsing logic.
+func (f *TelegramChannelFetcher) performFetch(client *http.Client, req *http.Request, since time.Time) ([]NewsItem, error) {
+	body, pageURL, err := getDocument(client, req, f.MaxBodySize, "")
+	if err != nil {
+		return nil, err
+	}
+	return ParseTelegramChannel(bytes.NewReader(body), pageURL, since, f.SourceName)
+}
+
+// ParseTelegramChannel extracts the posts published after since from a t.me/s/<channel> page.
+// Relative links are resolved against pageURL.
+func ParseTelegramChannel(r io.Reader, pageURL *url.URL, since time.Time, sourceName string) ([]NewsItem, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing channel page: %w", err)
+	}
+
+	channelTitle := collapseSpace(doc.Find(".tgme_channel_info_header_title").First().Text())
+	if channelTitle == "" {
+		channelTitle = doc.Find(`meta[property="og:title"]`).AttrOr("content", "")
+	}
+	slog.Info("Fetching news", "source", sourceName, "channel", channelTitle)
+
+	var newsItems []NewsItem
+	doc.Find(".tgme_widget_message[data-post]").Each(func(_ int, message *goquery.Selection) {
+		date := message.Find(".tgme_widget_message_date time[datetime]").First()
+		publishedOn, err := time.Parse(time.RFC3339, date.AttrOr("datetime", ""))
+		if err != nil || !publishedOn.After(since) {
+			return
+		}
+
+		textElement := message.Find(".tgme_widget_message_text").First()
+		rawContent, _ := textElement.Html()
+		content := telegramMessageText(textElement)
+
+		var media []Media
+		message.Find(".tgme_widget_message_photo_wrap, .tgme_widget_message_video_thumb, .link_preview_image").Each(func(_ int, element *goquery.Selection) {
+			if match := backgroundImageRe.FindStringSubmatch(element.AttrOr("style", "")); match != nil {
+				media = append(media, Media{URL: resolveURL(pageURL, match[1]), Medium: "image"})
+			}
+		})
+		if content == "" && len(media) == 0 {
+			return
+		}
+
+		link := message.Find("a.tgme_widget_message_date").AttrOr("href", "")
+		if link == "" {
+			link = "https://t.me/" + message.AttrOr("data-post", "")
+		}
+		item := NewsItem{
+			GUID:        message.AttrOr("data-post", ""),
+			Title:       firstLineTitle(content, channelTitle),
+			Link:        resolveURL(pageURL, link),
+			Content:     content,
+			RawContent:  rawContent,
+			PublishedOn: publishedOn,
+			Media:       media,
+			SourceName:  sourceName,
+			FeedTitle:   channelTitle,
+		}
+		if author := collapseSpace(message.Find(".tgme_widget_message_from_author").First().Text()); author != "" {
+			item.Authors = []string{author}
+		}
+		if len(media) > 0 {
+			item.ImageURL = media[0].URL
+		}
+		newsItems = append(newsItems, item)
+	})
+	return newsItems, nil
+}
+
+// telegramMessageText returns the text of a post, keeping its line breaks.
+func telegramMessageText(text *goquery.Selection) string {
+	if text.Length() == 0 {
+		return ""
+	}
+	clone := text.Clone()
+	clone.Find("br").ReplaceWithHtml("\n")
+	var lines []string
+	for _, line := range strings.Split(clone.Text(), "\n") {
+		lines = append(lines, collapseSpace(line))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
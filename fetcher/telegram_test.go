@@ -0,0 +1,141 @@
+package fetcher
+
+import (
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseTelegramFixture parses a recorded t.me/s/<channel> page from testdata.
+func parseTelegramFixture(t *testing.T, name, channel string, since time.Time) []NewsItem {
+	t.Helper()
+	file, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	pageURL, err := url.Parse("https://t.me/s/" + channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ParseTelegramChannel(file, pageURL, since, "NoNoise")
+	if err != nil {
+		t.Fatalf("ParseTelegramChannel: %v", err)
+	}
+	return items
+}
+
+func TestParseTelegramChannel(t *testing.T) {
+	since := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	items := parseTelegramFixture(t, "telegram_channel.html", "nonoisedaily", since)
+
+	want := []NewsItem{
+		{
+			// A text post keeps its line breaks; its first line is the title
+			GUID:        "nonoisedaily/101",
+			Title:       "Central bank raises rates",
+			Link:        "https://t.me/nonoisedaily/101",
+			Content:     "Central bank raises rates\n\nThe key rate goes up by 2 points & the rouble steadies.\nDetails: example.com/rates",
+			PublishedOn: time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+			Authors:     []string{"Anna Petrova"},
+			SourceName:  "NoNoise",
+			FeedTitle:   "NoNoise Daily",
+		},
+		{
+			// A photo-only album is titled after the channel, its first photo being the image
+			GUID:        "nonoisedaily/102",
+			Title:       "NoNoise Daily",
+			Link:        "https://t.me/nonoisedaily/102",
+			PublishedOn: time.Date(2026, 10, 17, 10, 15, 0, 0, time.UTC),
+			ImageURL:    "https://cdn4.telesco.pe/file/album-first.jpg",
+			Media: []Media{
+				{URL: "https://cdn4.telesco.pe/file/album-first.jpg", Medium: "image"},
+				{URL: "https://cdn4.telesco.pe/file/album-second.jpg", Medium: "image"},
+			},
+			SourceName: "NoNoise",
+			FeedTitle:  "NoNoise Daily",
+		},
+		{
+			// The image of a link preview stands in for a photo
+			GUID:        "nonoisedaily/104",
+			Title:       "Satellite images of the flooded delta",
+			Link:        "https://t.me/nonoisedaily/104",
+			Content:     "Satellite images of the flooded delta",
+			PublishedOn: time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC),
+			ImageURL:    "https://cdn4.telesco.pe/file/floods-preview.jpg",
+			Media:       []Media{{URL: "https://cdn4.telesco.pe/file/floods-preview.jpg", Medium: "image"}},
+			SourceName:  "NoNoise",
+			FeedTitle:   "NoNoise Daily",
+		},
+	}
+	compareItems(t, items, want)
+
+	if raw := items[0].RawContent; !strings.Contains(raw, "<b>Central bank   raises rates</b>") || !strings.Contains(raw, `href="https://example.com/rates"`) {
+		t.Errorf("RawContent = %q, want the post's markup", raw)
+	}
+}
+
+func TestParseTelegramChannelForwarded(t *testing.T) {
+	since := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	items := parseTelegramFixture(t, "telegram_forwarded.html", "nonoisedigest", since)
+
+	// A forwarded post is the forwarding channel's own, with the video thumbnail as its image
+	want := []NewsItem{{
+		GUID:        "nonoisedigest/7",
+		Title:       "Rocket launch moved to Friday",
+		Link:        "https://t.me/nonoisedigest/7",
+		Content:     "Rocket launch moved to Friday\nWeather over the pad is the reason.",
+		PublishedOn: time.Date(2026, 10, 17, 9, 45, 0, 0, time.UTC),
+		ImageURL:    "https://cdn4.telesco.pe/file/launch-thumb.jpg",
+		Media:       []Media{{URL: "https://cdn4.telesco.pe/file/launch-thumb.jpg", Medium: "image"}},
+		SourceName:  "NoNoise",
+		FeedTitle:   "NoNoise Digest",
+	}}
+	compareItems(t, items, want)
+}
+
+func TestParseTelegramChannelSince(t *testing.T) {
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []string
+	}{
+		{"all", time.Time{}, []string{"nonoisedaily/100", "nonoisedaily/101", "nonoisedaily/102", "nonoisedaily/104"}},
+		{"cutoff between posts", time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC), []string{"nonoisedaily/102", "nonoisedaily/104"}},
+		{"cutoff at a post", time.Date(2026, 10, 17, 10, 15, 0, 0, time.UTC), []string{"nonoisedaily/104"}},
+		{"after the last post", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var guids []string
+			for _, item := range parseTelegramFixture(t, "telegram_channel.html", "nonoisedaily", tt.since) {
+				guids = append(guids, item.GUID)
+			}
+			if !reflect.DeepEqual(guids, tt.want) {
+				t.Errorf("GUIDs = %q, want %q", guids, tt.want)
+			}
+		})
+	}
+}
+
+// compareItems compares parsed items with the wanted ones, apart from their raw content.
+func compareItems(t *testing.T, got, want []NewsItem) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d", len(got), len(want))
+	}
+	for i := range want {
+		item := got[i]
+		item.RawContent = ""
+		if !item.PublishedOn.Equal(want[i].PublishedOn) {
+			t.Errorf("item %d: PublishedOn = %v, want %v", i, item.PublishedOn, want[i].PublishedOn)
+		}
+		item.PublishedOn = want[i].PublishedOn
+		if !reflect.DeepEqual(item, want[i]) {
+			t.Errorf("item %d:\n got %+v\nwant %+v", i, item, want[i])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Follow-up policies configurable per channel.
+const (
+	FollowUpReply      = "reply"      // post follow-ups as replies to the original post
+	FollowUpSkip       = "skip"       // do not post follow-ups at all
+	FollowUpStandalone = "standalone" // post follow-ups as regular posts, without classifying stories
+	FollowUpOff        = "off"        // disable detection, post everything
+)
+
+// FollowUpDetector decides how a candidate post relates to what the channel already published.
+type FollowUpDetector struct {
+	geminiService *GeminiService
+	archive       *PostArchive
+	notifier      *AdminNotifier
+	config        *Config
+}
+
+// NewFollowUpDetector creates a new FollowUpDetector.
+func NewFollowUpDetector(geminiService *GeminiService, archive *PostArchive, notifier *AdminNotifier, config *Config) *FollowUpDetector {
+	return &FollowUpDetector{
+		geminiService: geminiService,
+		archive:       archive,
+		notifier:      notifier,
+		config:        config,
+	}
+}
+
+// policy returns the follow-up policy of a channel.
+func (d *FollowUpDetector) policy(channelID string) string {
+	if policy, ok := d.config.FollowUpPolicies[channelID]; ok {
+		return policy
+	}
+	return FollowUpReply
+}
+
+// Check classifies the post against the channel's recent posts and threads follow-ups as replies
+// according to the channel's policy. It reports whether the post should be published. Gemini is
+// only asked when the policy acts on follow-ups and the channel has recent posts to compare with.
+func (d *FollowUpDetector) Check(post *Post) bool {
+	policy := d.policy(post.ChannelID)
+	if policy == FollowUpOff || policy == FollowUpStandalone {
+		return true
+	}
+
+	recentPosts, err := d.archive.Recent(post.ChannelID, time.Now().Add(-d.config.FollowUpWindow))
+	if err != nil {
+		LogError("Failed to load recent posts, treating story as new", err, "channel_id", post.ChannelID)
+		return true
+	}
+	if len(recentPosts) == 0 {
+		return true
+	}
+
+	classification, err := d.geminiService.ClassifyStory(post.Text, recentPosts, d.config.RetryAttempts, d.config.RetryDelay)
+	if err != nil {
+		LogError("Failed to classify story, treating it as new", err, "channel_id", post.ChannelID, "source", post.SourceName)
+		return true
+	}
+	LogInfo("Story classified", "channel_id", post.ChannelID, "source", post.SourceName, "kind", classification.Kind, "related_message_id", classification.RelatedMessageID, "reason", classification.Reason)
+
+	switch classification.Kind {
+	case StoryDuplicate:
+		d.notifier.Skipped(post.SourceName, fmt.Sprintf("Skipped duplicate of message %d in %s from %s: %s", classification.RelatedMessageID, post.ChannelID, post.SourceName, classification.Reason))
+		return false
+	case StoryFollowUp:
+		switch policy {
+		case FollowUpSkip:
+			d.notifier.Skipped(post.SourceName, fmt.Sprintf("Skipped follow-up to message %d in %s from %s: %s", classification.RelatedMessageID, post.ChannelID, post.SourceName, classification.Reason))
+			return false
+		case FollowUpReply:
+			post.ReplyToMessageID = classification.RelatedMessageID
+		}
+	}
+	return true
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,38 +20,195 @@ import (
 // GeminiService is a service for interacting with the Gemini API.
 type GeminiService struct {
 	genaiClient *genai.Client
-	prompt      string
 }
 
 // NewGeminiService creates a new GeminiService.
-func NewGeminiService(apiKey string, prompt string) *GeminiService {
+func NewGeminiService(apiKey string) *GeminiService {
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
 		log.Fatalf("failed to create genai client: %v", err)
 	}
-	return &GeminiService{genaiClient: client, prompt: prompt}
+	return &GeminiService{genaiClient: client}
 }
 
-// AnalyzeNews analyzes news articles using the Gemini API.
-func (s *GeminiService) AnalyzeNews(items []fetcher.NewsItem, attempts int, delay time.Duration) (string, string, error) {
-	var newsContent string
-	for _, item := range items {
-		imagePart := ""
-		if item.ImageURL != "" {
-			imagePart = fmt.Sprintf("Image: %s\n", item.ImageURL)
+// AnalyzeNews runs an analysis prompt built by the PromptBuilder and returns the image URL
+// the model put on the first line, if any, and the analysis text.
+func (s *GeminiService) AnalyzeNews(prompt string, attempts int, delay time.Duration) (string, string, error) {
+	analysis, err := s.generate(prompt, false, attempts, delay)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Extract image URL from the first line and the rest of the analysis
+	parts := strings.SplitN(analysis, "\n", 2)
+	if len(parts) > 0 && (strings.HasPrefix(parts[0], "http://") || strings.HasPrefix(parts[0], "https://")) {
+		imageURL := parts[0]
+		analysisText := ""
+		if len(parts) > 1 {
+			analysisText = parts[1]
+		}
+		return imageURL, analysisText, nil
+	}
+
+	return "", analysis, nil
+}
+
+// Story classifications relative to recently published posts.
+const (
+	StoryNew       = "new"
+	StoryFollowUp  = "follow_up"
+	StoryDuplicate = "duplicate"
+)
+
+// StoryClassification is the verdict on how a candidate post relates to recently published posts.
+type StoryClassification struct {
+	Kind             string `json:"kind"`
+	RelatedMessageID int    `json:"related_message_id"`
+	Reason           string `json:"reason"`
+}
+
+// ClassifyStory asks Gemini whether a candidate post is a new story, a follow-up or a duplicate
+// of one of the recently published posts.
+func (s *GeminiService) ClassifyStory(candidate string, recentPosts []ArchivedPost, attempts int, delay time.Duration) (*StoryClassification, error) {
+	if len(recentPosts) == 0 {
+		return &StoryClassification{Kind: StoryNew}, nil
+	}
+
+	var b strings.Builder
+	b.WriteString(StoryClassificationPrompt)
+	b.WriteString("\n\nRecent posts:\n")
+	for _, post := range recentPosts {
+		fmt.Fprintf(&b, "\n[message_id=%d, posted %s]\n%s\n", post.MessageID, post.PostedAt.Format(time.RFC3339), post.Text)
+	}
+	fmt.Fprintf(&b, "\nCandidate:\n%s\n", candidate)
+
+	response, err := s.generate(b.String(), true, attempts, delay)
+	if err != nil {
+		return nil, err
+	}
+
+	var classification StoryClassification
+	if err := json.Unmarshal([]byte(response), &classification); err != nil {
+		return nil, fmt.Errorf("failed to decode story classification: %w", err)
+	}
+
+	switch classification.Kind {
+	case StoryFollowUp, StoryDuplicate:
+		for _, post := range recentPosts {
+			if post.MessageID == classification.RelatedMessageID {
+				return &classification, nil
+			}
+		}
+		return nil, fmt.Errorf("story classified as %s of unknown message %d", classification.Kind, classification.RelatedMessageID)
+	case StoryNew:
+		return &classification, nil
+	default:
+		return nil, fmt.Errorf("unknown story classification %q", classification.Kind)
+	}
+}
+
+// IdentifySources asks Gemini which of the items a post was written from and returns their indices.
+func (s *GeminiService) IdentifySources(post string, items []fetcher.NewsItem, attempts int, delay time.Duration) ([]int, error) {
+	var b strings.Builder
+	b.WriteString(SourceIdentificationPrompt)
+	fmt.Fprintf(&b, "\n\nPost:\n%s\n\nArticles:\n", post)
+	for i, item := range items {
+		fmt.Fprintf(&b, "\n[index=%d]\nTitle: %s\nContent: %s\n", i, item.Title, truncateRunes(item.Content, ContentPreviewLimit))
+	}
+
+	response, err := s.generate(b.String(), true, attempts, delay)
+	if err != nil {
+		return nil, err
+	}
+
+	var indices []int
+	if err := json.Unmarshal([]byte(response), &indices); err != nil {
+		return nil, fmt.Errorf("failed to decode source indices: %w", err)
+	}
+
+	valid := indices[:0]
+	for _, index := range indices {
+		if index >= 0 && index < len(items) {
+			valid = append(valid, index)
 		}
-		newsContent += fmt.Sprintf("Title: %s\n%sContent: %s\n\n", item.Title, imagePart, item.RawContent)
 	}
+	return valid, nil
+}
 
-	fullPrompt := fmt.Sprintf(s.prompt, newsContent)
+// ItemScore is the significance score Gemini assigned to a news item.
+type ItemScore struct {
+	Index int `json:"index"`
+	Score int `json:"score"`
+}
+
+// ScoreItems asks Gemini to rate the long-term significance of each item on a scale from 1 to 10.
+// The returned slice is parallel to items; unscored items get 0.
+func (s *GeminiService) ScoreItems(items []fetcher.NewsItem, attempts int, delay time.Duration) ([]int, error) {
+	var b strings.Builder
+	b.WriteString(ItemScoringPrompt)
+	b.WriteString("\n\nItems:\n")
+	for i, item := range items {
+		fmt.Fprintf(&b, "\n[index=%d]\nTitle: %s\nContent: %s\n", i, item.Title, item.Content)
+	}
+
+	response, err := s.generate(b.String(), true, attempts, delay)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemScores []ItemScore
+	if err := json.Unmarshal([]byte(response), &itemScores); err != nil {
+		return nil, fmt.Errorf("failed to decode item scores: %w", err)
+	}
+
+	scores := make([]int, len(items))
+	for _, itemScore := range itemScores {
+		if itemScore.Index >= 0 && itemScore.Index < len(items) {
+			scores[itemScore.Index] = itemScore.Score
+		}
+	}
+	return scores, nil
+}
+
+// RewritePost asks Gemini to rewrite a post in another language.
+func (s *GeminiService) RewritePost(post, language string, attempts int, delay time.Duration) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, PostRewritePrompt, language)
+	fmt.Fprintf(&b, "\n\nPost:\n%s\n", post)
+
+	return s.generate(b.String(), false, attempts, delay)
+}
+
+// WriteDigest asks Gemini to write a single digest post summarizing the given stories.
+// Without a language, the digest is written in the language of the stories.
+func (s *GeminiService) WriteDigest(entries []DigestEntry, period, language string, attempts int, delay time.Duration) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, DigestPrompt, period)
+	if language != "" {
+		fmt.Fprintf(&b, "\nWrite the digest in %s regardless of the language of the stories.", language)
+	}
+	b.WriteString("\n\nStories:\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "\nSource: %s\nTitle: %s\nLink: %s\nPublished: %s\nContent: %s\n",
+			entry.SourceName, entry.Title, entry.Link, entry.PublishedOn.Format(time.RFC3339), entry.Content)
+	}
 
-	analysis, err := utils.Retry(attempts, delay, func() (string, error) {
+	return s.generate(b.String(), false, attempts, delay)
+}
+
+// generate sends a prompt to Gemini and returns the first text part of the response.
+// With jsonOutput set, the model is asked to respond with a JSON document.
+func (s *GeminiService) generate(prompt string, jsonOutput bool, attempts int, delay time.Duration) (string, error) {
+	return utils.Retry(attempts, delay, func() (string, error) {
 		model := s.genaiClient.GenerativeModel(GeminiModel)
+		if jsonOutput {
+			model.ResponseMIMEType = "application/json"
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), APITimeout)
 		defer cancel()
 
-		resp, err := model.GenerateContent(ctx, genai.Text(fullPrompt))
+		resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 		if err != nil {
 			return "", fmt.Errorf("failed to generate content: %w", err)
 		}
@@ -62,23 +222,29 @@ func (s *GeminiService) AnalyzeNews(items []fetcher.NewsItem, attempts int, dela
 		}
 		return "", nil
 	})
+}
 
-	if err != nil {
-		return "", "", err
-	}
+var (
+	scoreLineRe = regexp.MustCompile(`(?im)^\s*(?:score|оценка)\s*:\s*(\d+)(?:\s*/\s*10)?\s*$\n?`)
+	tagsLineRe  = regexp.MustCompile(`(?im)^\s*(?:tags|теги)\s*:\s*(.*?)\s*$\n?`)
+)
 
-	// Extract image URL from the first line and the rest of the analysis
-	parts := strings.SplitN(analysis, "\n", 2)
-	if len(parts) > 0 && (strings.HasPrefix(parts[0], "http://") || strings.HasPrefix(parts[0], "https://")) {
-		imageURL := parts[0]
-		analysisText := ""
-		if len(parts) > 1 {
-			analysisText = parts[1]
+// ParseAnalysis extracts the optional "Score: N" and "Tags: a, b" lines from an analysis
+// and returns the remaining text together with the extracted values.
+func ParseAnalysis(analysis string) (text string, score int, tags []string) {
+	if match := scoreLineRe.FindStringSubmatch(analysis); match != nil {
+		score, _ = strconv.Atoi(match[1])
+		analysis = scoreLineRe.ReplaceAllString(analysis, "")
+	}
+	if match := tagsLineRe.FindStringSubmatch(analysis); match != nil {
+		for _, tag := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ',' || r == '#' }) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
 		}
-		return imageURL, analysisText, nil
+		analysis = tagsLineRe.ReplaceAllString(analysis, "")
 	}
-
-	return "", analysis, nil
+	return strings.TrimSpace(analysis), score, tags
 }
 
 // Close closes the Gemini client.
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"news/fetcher"
+	"news/store"
+)
+
+// healthReceivingOperation is the operation under which sources that stopped producing items are reported.
+const healthReceivingOperation = "receiving new items"
+
+// SourceHealth is the persisted health of a single source.
+type SourceHealth struct {
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastAttempt         time.Time `json:"last_attempt,omitzero"`
+	LastSuccess         time.Time `json:"last_success,omitzero"`
+	// NextAttempt is when a failing source is fetched again.
+	NextAttempt time.Time `json:"next_attempt,omitzero"`
+	// LastItemAt is the publication time of the newest item fetched.
+	LastItemAt time.Time `json:"last_item_at,omitzero"`
+	// Watermark is the publication time of the newest processed dated item, from which the next run fetches.
+	Watermark time.Time `json:"watermark,omitzero"`
+	// SeenItems maps the GUIDs of processed items to when they were last fetched, so items fetched
+	// again, because of the watermark overlap or as undated items, are not taken for new ones.
+	SeenItems map[string]time.Time `json:"seen_items,omitempty"`
+	// FeedTitle is the title of the source's feed, by which a moved feed is recognized.
+	FeedTitle string `json:"feed_title,omitempty"`
+	// Relocation records the search for the source's feed after its configured URL was gone.
+	Relocation *FeedRelocation `json:"relocation,omitempty"`
+	// ItemCount counts the items processed since TrackedSince, for the average rate.
+	ItemCount    int       `json:"item_count,omitempty"`
+	TrackedSince time.Time `json:"tracked_since"`
+	Disabled     bool      `json:"disabled,omitempty"`
+	DisabledAt   time.Time `json:"disabled_at,omitzero"`
+}
+
+// FeedRelocation records where a feed gone from its configured URL was found.
+type FeedRelocation struct {
+	// From is the configured URL that was gone.
+	From string `json:"from"`
+	// To is the feed's new URL, or empty if no feed of the site matched the source's.
+	To        string    `json:"to,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// ItemsPerDay returns the average number of new items a day since tracking began.
+func (h *SourceHealth) ItemsPerDay() float64 {
+	days := time.Since(h.TrackedSince).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	return float64(h.ItemCount) / days
+}
+
+// healthState is the persisted health of all sources.
+type healthState struct {
+	Sources map[string]*SourceHealth `json:"sources"`
+}
+
+// HealthTracker records the health of sources, backs off from failing ones and disables dead ones.
+type HealthTracker struct {
+	store        *store.JSONFile[healthState]
+	notifier     *AdminNotifier
+	disableAfter int
+	backoffMax   time.Duration
+	staleAfter   time.Duration
+	// pending holds the new items of each source's fetch in this run until they are processed.
+	pending map[string]pendingItems
+}
+
+// pendingItems are new items fetched at fetchedAt, published after since, that are not yet processed.
+type pendingItems struct {
+	since     time.Time
+	fetchedAt time.Time
+	items     []fetcher.NewsItem
+}
+
+// NewHealthTracker creates a new HealthTracker persisting its state in the configured state directory.
+func NewHealthTracker(notifier *AdminNotifier, config *Config) *HealthTracker {
+	return &HealthTracker{
+		store:        store.NewJSONFile[healthState](filepath.Join(config.StateDir, "health.json")),
+		notifier:     notifier,
+		disableAfter: config.SourceDisableAfter,
+		backoffMax:   config.SourceBackoffMax,
+		staleAfter:   config.SourceStaleAfter,
+		pending:      make(map[string]pendingItems),
+	}
+}
+
+// ShouldFetch reports whether a source is due to be fetched, and why not if it is not.
+func (t *HealthTracker) ShouldFetch(sourceName string) (bool, string) {
+	state, err := t.store.Load()
+	if err != nil {
+		LogError("Failed to load source health", err, "source", sourceName)
+		return true, ""
+	}
+	health := state.Sources[sourceName]
+	switch {
+	case health == nil:
+		return true, ""
+	case health.Disabled:
+		return false, "disabled"
+	case time.Now().Before(health.NextAttempt):
+		return false, fmt.Sprintf("backing off until %s", health.NextAttempt.Format(time.RFC3339))
+	}
+	return true, ""
+}
+
+// Since returns the time from which a source's items are fetched: its watermark, less the
+// overlap for feeds that backdate their items, but no further back than the lookback period.
+func (t *HealthTracker) Since(sourceName string, lookback, overlap time.Duration) time.Time {
+	since := time.Now().Add(-lookback)
+	state, err := t.store.Load()
+	if err != nil {
+		LogError("Failed to load source health", err, "source", sourceName)
+		return since
+	}
+	if health := state.Sources[sourceName]; health != nil && !health.Watermark.IsZero() {
+		if watermark := health.Watermark.Add(-overlap); watermark.After(since) {
+			return watermark
+		}
+	}
+	return since
+}
+
+// Success records a successful fetch of the items published after since, returning those not
+// seen by earlier runs. They count as seen once Processed is called; items seen before are kept
+// as seen as long as they are fetched. It alerts if the source has been without new items for
+// longer than its stale period, or zero for the default.
+func (t *HealthTracker) Success(sourceName string, since time.Time, items []fetcher.NewsItem, staleAfter time.Duration) []fetcher.NewsItem {
+	now := time.Now()
+	var previousItemAt time.Time
+	var stale bool
+	var newItems []fetcher.NewsItem
+	err := t.update(sourceName, func(health *SourceHealth) {
+		previousItemAt = health.LastItemAt
+		health.ConsecutiveFailures = 0
+		health.LastError = ""
+		health.NextAttempt = time.Time{}
+		health.LastAttempt = now
+		health.LastSuccess = now
+
+		if len(items) > 0 && items[0].FeedTitle != "" {
+			health.FeedTitle = items[0].FeedTitle
+		}
+		for _, item := range items {
+			if _, seen := health.SeenItems[itemKey(item)]; seen {
+				health.SeenItems[itemKey(item)] = now
+				continue
+			}
+			newItems = append(newItems, item)
+			if item.PublishedOn.After(health.LastItemAt) {
+				health.LastItemAt = item.PublishedOn
+			}
+		}
+
+		if staleAfter <= 0 {
+			staleAfter = t.staleAfter
+		}
+		lastItemAt := health.LastItemAt
+		if lastItemAt.IsZero() {
+			lastItemAt = health.TrackedSince
+		}
+		stale = staleAfter > 0 && now.Sub(lastItemAt) > staleAfter
+	})
+	if err != nil {
+		LogError("Failed to update source health", err, "source", sourceName)
+		return items
+	}
+	t.pending[sourceName] = pendingItems{since: since, fetchedAt: now, items: newItems}
+
+	if !stale {
+		t.notifier.Resolve(sourceName, healthReceivingOperation)
+		return newItems
+	}
+	lastItem := "tracking began"
+	if !previousItemAt.IsZero() {
+		lastItem = previousItemAt.Format("2006-01-02 15:04 MST")
+	}
+	t.notifier.Error(sourceName, healthReceivingOperation, fmt.Errorf("no new items since %s", lastItem))
+	return newItems
+}
+
+// Keep narrows the new items of the source's last successful fetch to those its filter kept. The
+// others are not marked seen, so they are considered again while they are within the fetch
+// window, for instance once their score has risen.
+func (t *HealthTracker) Keep(sourceName string, items []fetcher.NewsItem) {
+	if pending, ok := t.pending[sourceName]; ok {
+		pending.items = items
+		t.pending[sourceName] = pending
+	}
+}
+
+// Processed marks the new items of the source's last successful fetch as seen, advancing its
+// watermark. Until then, a run that fails to analyze them considers them again next time. Seen
+// items not fetched since the start of the fetch window are forgotten: dated ones are too old to
+// be fetched again, and undated ones have left the source.
+func (t *HealthTracker) Processed(sourceName string) {
+	pending, ok := t.pending[sourceName]
+	if !ok {
+		return
+	}
+	delete(t.pending, sourceName)
+
+	err := t.update(sourceName, func(health *SourceHealth) {
+		if health.SeenItems == nil {
+			health.SeenItems = make(map[string]time.Time)
+		}
+		for key, fetchedAt := range health.SeenItems {
+			if fetchedAt.Before(pending.since) {
+				delete(health.SeenItems, key)
+			}
+		}
+		for _, item := range pending.items {
+			health.SeenItems[itemKey(item)] = pending.fetchedAt
+			health.ItemCount++
+			// The fetch time of undated items would skip the dated items published before it
+			if !item.Undated && item.PublishedOn.After(health.Watermark) {
+				health.Watermark = item.PublishedOn
+			}
+		}
+	})
+	if err != nil {
+		LogError("Failed to update source health", err, "source", sourceName)
+	}
+}
+
+// itemKey identifies an item among those seen from its source. Items with neither a GUID nor a
+// link are told apart by a hash of their text and, unless undated, their publication time.
+func itemKey(item fetcher.NewsItem) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	if item.Link != "" {
+		return item.Link
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s", item.Title, item.Content)
+	if !item.Undated {
+		fmt.Fprintf(hash, "\x00%d", item.PublishedOn.Unix())
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// Relocation returns where the source's feed was looked for after feedURL was gone, or nil if it
+// has not been.
+func (t *HealthTracker) Relocation(sourceName, feedURL string) *FeedRelocation {
+	state, err := t.store.Load()
+	if err != nil {
+		LogError("Failed to load source health", err, "source", sourceName)
+		return nil
+	}
+	health := state.Sources[sourceName]
+	if health == nil || health.Relocation == nil || health.Relocation.From != feedURL {
+		return nil
+	}
+	return health.Relocation
+}
+
+// SetRelocation records where the source's feed was looked for after its URL was gone.
+func (t *HealthTracker) SetRelocation(sourceName string, relocation *FeedRelocation) {
+	err := t.update(sourceName, func(health *SourceHealth) {
+		health.Relocation = relocation
+	})
+	if err != nil {
+		LogError("Failed to update source health", err, "source", sourceName)
+	}
+}
+
+// RecognizesFeed reports whether a feed with the title and items is the source's: it has the
+// title of the source's feed, or items seen from the source.
+func (t *HealthTracker) RecognizesFeed(sourceName, title string, items []fetcher.NewsItem) bool {
+	state, err := t.store.Load()
+	if err != nil {
+		LogError("Failed to load source health", err, "source", sourceName)
+		return false
+	}
+	health := state.Sources[sourceName]
+	if health == nil {
+		return false
+	}
+	if title = strings.Join(strings.Fields(title), " "); title != "" &&
+		strings.EqualFold(title, strings.Join(strings.Fields(health.FeedTitle), " ")) {
+		return true
+	}
+	for _, item := range items {
+		if _, seen := health.SeenItems[itemKey(item)]; seen {
+			return true
+		}
+		if _, seen := health.SeenItems[item.Link]; seen && item.Link != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Failure records a failed fetch, backing off exponentially from the source and disabling it
+// after too many consecutive failures.
+func (t *HealthTracker) Failure(sourceName string, fetchErr error) {
+	now := time.Now()
+	var failures int
+	var disabled bool
+	err := t.update(sourceName, func(health *SourceHealth) {
+		health.ConsecutiveFailures++
+		health.LastError = fetchErr.Error()
+		health.LastAttempt = now
+		failures = health.ConsecutiveFailures
+
+		backoff := t.backoffMax
+		if shift := failures - 1; shift < 16 && SourceBackoffBase<<shift < backoff {
+			backoff = SourceBackoffBase << shift
+		}
+		health.NextAttempt = now.Add(backoff)
+
+		if t.disableAfter > 0 && failures >= t.disableAfter {
+			health.Disabled = true
+			health.DisabledAt = now
+			disabled = true
+		}
+	})
+	if err != nil {
+		LogError("Failed to update source health", err, "source", sourceName)
+		return
+	}
+	if disabled {
+		LogInfo("Source disabled", "source", sourceName, "failures", failures)
+		t.notifier.SourceDisabled(sourceName, failures, fetchErr)
+	}
+}
+
+// SetDisabled disables a source or enables it again, resetting its failures and backoff.
+func (t *HealthTracker) SetDisabled(sourceName string, disabled bool) error {
+	return t.update(sourceName, func(health *SourceHealth) {
+		health.Disabled = disabled
+		if disabled {
+			health.DisabledAt = time.Now()
+			return
+		}
+		health.DisabledAt = time.Time{}
+		health.ConsecutiveFailures = 0
+		health.NextAttempt = time.Time{}
+	})
+}
+
+// All returns the health of all tracked sources.
+func (t *HealthTracker) All() (map[string]*SourceHealth, error) {
+	state, err := t.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return state.Sources, nil
+}
+
+// update applies fn to the health of a source, starting to track it if it is new.
+func (t *HealthTracker) update(sourceName string, fn func(*SourceHealth)) error {
+	return t.store.Update(func(state *healthState) error {
+		if state.Sources == nil {
+			state.Sources = make(map[string]*SourceHealth)
+		}
+		health, ok := state.Sources[sourceName]
+		if !ok {
+			health = &SourceHealth{TrackedSince: time.Now()}
+			state.Sources[sourceName] = health
+		}
+		fn(health)
+		return nil
+	})
+}
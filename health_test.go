@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"news/fetcher"
+)
+
+// newTestHealthTracker creates a HealthTracker keeping its state in a temporary directory. It
+// never alerts the admin: sources do not go stale and are not disabled.
+func newTestHealthTracker(t *testing.T) *HealthTracker {
+	t.Helper()
+	config := &Config{StateDir: t.TempDir(), SourceBackoffMax: 2 * time.Hour}
+	return NewHealthTracker(NewAdminNotifier(nil, config), config)
+}
+
+// guids returns the GUIDs of the items.
+func guids(items []fetcher.NewsItem) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, item.GUID)
+	}
+	return result
+}
+
+func TestHealthTrackerSeenItems(t *testing.T) {
+	tracker := newTestHealthTracker(t)
+	since := time.Now().Add(-24 * time.Hour)
+	first := fetcher.NewsItem{GUID: "first", PublishedOn: since.Add(time.Hour)}
+	second := fetcher.NewsItem{GUID: "second", PublishedOn: since.Add(2 * time.Hour)}
+	third := fetcher.NewsItem{GUID: "third", PublishedOn: since.Add(3 * time.Hour)}
+
+	if got := guids(tracker.Success("src", since, []fetcher.NewsItem{first, second}, 0)); !slices.Equal(got, []string{"first", "second"}) {
+		t.Fatalf("first run: new items %q, want both", got)
+	}
+	// Items not processed, for instance because the analysis failed, are new again
+	if got := guids(tracker.Success("src", since, []fetcher.NewsItem{first, second}, 0)); !slices.Equal(got, []string{"first", "second"}) {
+		t.Fatalf("retry: new items %q, want both", got)
+	}
+	tracker.Processed("src")
+
+	if got := guids(tracker.Success("src", since, []fetcher.NewsItem{first, second, third}, 0)); !slices.Equal(got, []string{"third"}) {
+		t.Fatalf("next run: new items %q, want only the third", got)
+	}
+	// Items the filter dropped are not marked seen
+	tracker.Keep("src", nil)
+	tracker.Processed("src")
+	if got := guids(tracker.Success("src", since, []fetcher.NewsItem{first, second, third}, 0)); !slices.Equal(got, []string{"third"}) {
+		t.Errorf("after filtering: new items %q, want the filtered-out third again", got)
+	}
+	tracker.Processed("src")
+	if got := tracker.Success("src", since, []fetcher.NewsItem{first, second, third}, 0); len(got) != 0 {
+		t.Errorf("after processing: new items %q, want none", guids(got))
+	}
+}
+
+func TestHealthTrackerForgetsItemsNoLongerFetched(t *testing.T) {
+	tracker := newTestHealthTracker(t)
+	since := time.Now().Add(-24 * time.Hour)
+	gone := fetcher.NewsItem{GUID: "gone", Undated: true}
+	kept := fetcher.NewsItem{GUID: "kept", Undated: true}
+
+	tracker.Success("src", since, []fetcher.NewsItem{gone, kept}, 0)
+	tracker.Processed("src")
+
+	// The next fetch window starts after the first run; only the item fetched again stays seen
+	time.Sleep(time.Millisecond)
+	windowStart := time.Now()
+	time.Sleep(time.Millisecond)
+	if got := tracker.Success("src", windowStart, []fetcher.NewsItem{kept}, 0); len(got) != 0 {
+		t.Fatalf("new items %q, want none", guids(got))
+	}
+	tracker.Processed("src")
+
+	sources, err := tracker.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, seen := sources["src"].SeenItems["gone"]; seen {
+		t.Error("item no longer fetched is still seen")
+	}
+	if _, seen := sources["src"].SeenItems["kept"]; !seen {
+		t.Error("item fetched again is no longer seen")
+	}
+}
+
+func TestHealthTrackerWatermark(t *testing.T) {
+	tracker := newTestHealthTracker(t)
+	lookback, overlap := 24*time.Hour, 2*time.Hour
+
+	before := time.Now()
+	if got := tracker.Since("src", lookback, overlap); got.Before(before.Add(-lookback)) || got.After(time.Now().Add(-lookback)) {
+		t.Fatalf("Since without a watermark = %v, want the lookback period", got)
+	}
+
+	newest := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	items := []fetcher.NewsItem{
+		{GUID: "older", PublishedOn: newest.Add(-time.Hour)},
+		{GUID: "newest", PublishedOn: newest},
+		// Undated items, published at the fetch time, do not move the watermark
+		{GUID: "undated", PublishedOn: time.Now(), Undated: true},
+	}
+	tracker.Success("src", time.Now().Add(-lookback), items, 0)
+	if got := tracker.Since("src", lookback, overlap); got.After(time.Now().Add(-lookback).Add(time.Second)) {
+		t.Fatalf("Since before processing = %v, want the lookback period", got)
+	}
+	tracker.Processed("src")
+
+	if got, want := tracker.Since("src", lookback, overlap), newest.Add(-overlap); !got.Equal(want) {
+		t.Errorf("Since = %v, want the watermark less the overlap, %v", got, want)
+	}
+	// The lookback period still bounds how far back a source is fetched
+	if got := tracker.Since("src", time.Hour, overlap); got.Before(before.Add(-time.Hour)) {
+		t.Errorf("Since with a short lookback = %v, want at most an hour ago", got)
+	}
+}
+
+func TestHealthTrackerBackoff(t *testing.T) {
+	tracker := newTestHealthTracker(t)
+	for i, want := range []time.Duration{15 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour, 2 * time.Hour} {
+		start := time.Now()
+		tracker.Failure("src", errors.New("connection refused"))
+		sources, err := tracker.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		health := sources["src"]
+		if health.ConsecutiveFailures != i+1 {
+			t.Errorf("failure %d: ConsecutiveFailures = %d", i+1, health.ConsecutiveFailures)
+		}
+		if backoff := health.NextAttempt.Sub(start); backoff < want || backoff > want+time.Second {
+			t.Errorf("failure %d: backoff %v, want %v", i+1, backoff, want)
+		}
+	}
+	if due, reason := tracker.ShouldFetch("src"); due || reason == "" {
+		t.Errorf("ShouldFetch while backing off = %v, %q", due, reason)
+	}
+
+	tracker.Success("src", time.Now().Add(-time.Hour), nil, 0)
+	if due, _ := tracker.ShouldFetch("src"); !due {
+		t.Error("ShouldFetch after a success = false")
+	}
+}
+
+func TestItemKey(t *testing.T) {
+	published := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		a, b  fetcher.NewsItem
+		equal bool
+	}{
+		{"by GUID", fetcher.NewsItem{GUID: "1", Link: "https://a.example"}, fetcher.NewsItem{GUID: "1", Link: "https://b.example"}, true},
+		{"by link", fetcher.NewsItem{Link: "https://a.example", Title: "A"}, fetcher.NewsItem{Link: "https://a.example", Title: "B"}, true},
+		{"different text", fetcher.NewsItem{Title: "A", PublishedOn: published}, fetcher.NewsItem{Title: "B", PublishedOn: published}, false},
+		{"same text and date", fetcher.NewsItem{Title: "A", Content: "x", PublishedOn: published}, fetcher.NewsItem{Title: "A", Content: "x", PublishedOn: published.In(time.Local)}, true},
+		{"same text, other date", fetcher.NewsItem{Title: "A", PublishedOn: published}, fetcher.NewsItem{Title: "A", PublishedOn: published.Add(time.Hour)}, false},
+		// Undated items are published at their fetch time, which changes every run
+		{"undated", fetcher.NewsItem{Title: "A", PublishedOn: published, Undated: true}, fetcher.NewsItem{Title: "A", PublishedOn: published.Add(time.Hour), Undated: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := itemKey(tt.a), itemKey(tt.b)
+			if a == "" || b == "" {
+				t.Fatalf("empty key: %q, %q", a, b)
+			}
+			if (a == b) != tt.equal {
+				t.Errorf("itemKey() = %q and %q, want equal %v", a, b, tt.equal)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,42 +16,171 @@ import (
 func processNewsSource(
 	fetcher fetcher.Fetcher,
 	geminiService *GeminiService,
-	telegramService *TelegramService,
+	promptBuilder *PromptBuilder,
+	reviewService *ReviewService,
+	followUpDetector *FollowUpDetector,
+	digestService *DigestService,
+	publisher *Publisher,
+	health *HealthTracker,
+	notifier *AdminNotifier,
 	config *Config,
-	sourceName string,
+	source *SourceConfig,
 	targetChannelIDs []string,
 ) {
+	sourceName := source.Name
+	notifier.Run(sourceName)
+
 	// Step 1: Fetch news
-	items, err := fetchNews(fetcher, sourceName, config)
+	items, err := fetchNews(fetcher, source, health, notifier, config)
+	if isDisallowed(err) {
+		// Retrying does not help until the source or the site's robots.txt changes
+		handleError(notifier, sourceName, err, robotsOperation)
+		return
+	}
 	if err != nil {
-		handleError(telegramService, config.TelegramChatID, sourceName, err, "fetching")
+		handleError(notifier, sourceName, err, "fetching")
 		return
 	}
+	notifier.Resolve(sourceName, "fetching")
+	notifier.Resolve(sourceName, robotsOperation)
 
 	// Step 2: Display content preview
 	displayContentPreview(items, sourceName)
 
 	// Step 3: Check if we have any items
 	if len(items) == 0 {
-		handleNoNews(telegramService, config.TelegramChatID, sourceName)
+		health.Processed(sourceName)
+		handleNoNews(notifier, sourceName)
+		return
+	}
+
+	// Step 4: Collect items for digest channels, which do not receive real-time posts
+	var realtimeChannelIDs, digestChannelIDs []string
+	for _, channelID := range targetChannelIDs {
+		if digestService.IsDigestChannel(channelID) {
+			digestChannelIDs = append(digestChannelIDs, channelID)
+		} else {
+			realtimeChannelIDs = append(realtimeChannelIDs, channelID)
+		}
+	}
+	// Items count as processed only if every channel got them, so failures are retried next run
+	collected := true
+	if len(digestChannelIDs) > 0 {
+		if err := digestService.Collect(sourceName, items, digestChannelIDs); err != nil {
+			handleError(notifier, sourceName, err, "collecting for digest")
+			collected = false
+		} else {
+			notifier.Resolve(sourceName, "collecting for digest")
+		}
+	}
+	if len(realtimeChannelIDs) == 0 {
+		if collected {
+			health.Processed(sourceName)
+		}
 		return
 	}
 
-	// Step 4: Analyze news with Gemini
-	geminiImageURL, analysis, err := analyzeNews(geminiService, items, sourceName, config)
+	// Step 5: Build the prompt for each channel, as channels differ in language and recent posts
+	prompts, err := buildChannelPrompts(promptBuilder, sourceName, realtimeChannelIDs, items)
 	if err != nil {
-		handleError(telegramService, config.TelegramChatID, sourceName, err, "analyzing")
+		handleError(notifier, sourceName, err, "analyzing")
 		return
 	}
 
-	// Step 5: Send notifications
-	sendNotifications(telegramService, config.TelegramChatID, analysis, geminiImageURL, items, targetChannelIDs, sourceName)
+	// Step 6: Analyze news with Gemini once per distinct prompt and send notifications
+	analyzed := true
+	for _, prompt := range prompts {
+		geminiImageURL, analysis, err := analyzeNews(geminiService, prompt.text, config)
+		if err != nil {
+			handleError(notifier, sourceName, err, "analyzing")
+			analyzed = false
+			continue
+		}
+		sendNotifications(geminiService, promptBuilder.Language(sourceName, prompt.channelIDs[0]), reviewService, followUpDetector, publisher, notifier, config, analysis, geminiImageURL, items, prompt.channelIDs, sourceName)
+	}
+	if !analyzed {
+		// Channels already posted to catch the story as a duplicate when the items are retried
+		return
+	}
+	notifier.Resolve(sourceName, "analyzing")
+	if collected {
+		health.Processed(sourceName)
+	}
 }
 
-// fetchNews retrieves news items from the given fetcher.
-func fetchNews(fetcher fetcher.Fetcher, sourceName string, config *Config) ([]fetcher.NewsItem, error) {
-	fmt.Printf("\n--- Fetching from %s ---\n", sourceName)
-	return fetcher.Fetch(time.Now().AddDate(0, 0, -1), config.RetryAttempts, config.RetryDelay)
+// channelPrompt is an analysis prompt and the channels it was rendered for.
+type channelPrompt struct {
+	text       string
+	channelIDs []string
+}
+
+// buildChannelPrompts renders the source's prompt for each channel. Channels whose prompts are the
+// same, such as all channels of a legacy prompt, share one analysis.
+func buildChannelPrompts(promptBuilder *PromptBuilder, sourceName string, channelIDs []string, items []fetcher.NewsItem) ([]*channelPrompt, error) {
+	var prompts []*channelPrompt
+	byText := make(map[string]*channelPrompt)
+	for _, channelID := range channelIDs {
+		text, err := promptBuilder.Build(sourceName, channelID, items)
+		if err != nil {
+			return nil, err
+		}
+		prompt, ok := byText[text]
+		if !ok {
+			prompt = &channelPrompt{text: text}
+			byText[text] = prompt
+			prompts = append(prompts, prompt)
+		}
+		prompt.channelIDs = append(prompt.channelIDs, channelID)
+	}
+	return prompts, nil
+}
+
+// fetchNews retrieves news items from the given fetcher, records the source's health and applies the source's filter.
+func fetchNews(fetcher fetcher.Fetcher, source *SourceConfig, health *HealthTracker, notifier *AdminNotifier, config *Config) ([]fetcher.NewsItem, error) {
+	fmt.Printf("\n--- Fetching from %s ---\n", source.Name)
+	lookback, overlap := config.Lookback, config.WatermarkOverlap
+	if source.LookbackHours > 0 {
+		lookback = time.Duration(source.LookbackHours) * time.Hour
+	}
+	if source.OverlapHours > 0 {
+		overlap = time.Duration(source.OverlapHours) * time.Hour
+	}
+	since := health.Since(source.Name, lookback, overlap)
+	items, err := fetcher.Fetch(since, config.RetryAttempts, config.RetryDelay)
+	if err != nil {
+		items, err = healFeed(source, since, err, health, notifier, config)
+	} else if source.isFeed() {
+		notifier.Resolve(source.Name, locatingFeedOperation)
+	}
+	if err != nil {
+		// A source robots.txt disallows is misconfigured rather than failing, so it is neither
+		// backed off from nor disabled
+		if !isDisallowed(err) {
+			health.Failure(source.Name, err)
+		}
+		return nil, err
+	}
+	items = health.Success(source.Name, since, items, time.Duration(source.StaleAfterHours)*time.Hour)
+	if source.Filter == nil {
+		return items, nil
+	}
+
+	items, filtered := source.Filter.Apply(items)
+	health.Keep(source.Name, items)
+	if filtered > 0 {
+		LogInfo("Items filtered out", "source", source.Name, "filtered", filtered, "kept", len(items))
+		notifier.Filtered(source.Name, filtered)
+	}
+	return items, nil
+}
+
+// robotsOperation is the operation under which sources robots.txt disallows are reported.
+const robotsOperation = "complying with robots.txt"
+
+// isDisallowed reports whether a fetch failed because the site's robots.txt disallows it.
+func isDisallowed(err error) bool {
+	var robotsErr *fetcher.RobotsError
+	return errors.As(err, &robotsErr)
 }
 
 // displayContentPreview shows a preview of the first news item's content.
@@ -62,17 +194,19 @@ func displayContentPreview(items []fetcher.NewsItem, _ string) {
 	}
 }
 
-// analyzeNews uses Gemini AI to analyze and summarize the news items.
-func analyzeNews(geminiService *GeminiService, items []fetcher.NewsItem, _ string, config *Config) (string, string, error) {
+// analyzeNews uses Gemini AI to analyze and summarize the news items with the rendered prompt.
+func analyzeNews(geminiService *GeminiService, prompt string, config *Config) (string, string, error) {
 	fmt.Println("--- Analyzing News with Gemini ---")
-	return geminiService.AnalyzeNews(items, config.RetryAttempts, config.RetryDelay)
+	return geminiService.AnalyzeNews(prompt, config.RetryAttempts, config.RetryDelay)
 }
 
 // sendNotifications sends the analysis to the specified Telegram channels.
-func sendNotifications(telegramService *TelegramService, adminChatID, analysis, geminiImageURL string, items []fetcher.NewsItem, targetChannelIDs []string, sourceName string) {
+// The analysis, written in analysisLanguage, is rewritten for channels configured for other languages.
+func sendNotifications(geminiService *GeminiService, analysisLanguage string, reviewService *ReviewService, followUpDetector *FollowUpDetector, publisher *Publisher, notifier *AdminNotifier, config *Config, analysis, geminiImageURL string, items []fetcher.NewsItem, targetChannelIDs []string, sourceName string) {
 	if analysis != "" && len(analysis) >= 34 {
 		fmt.Println(analysis)
-		sanitizedAnalysis := strings.ReplaceAll(analysis, TelegramMarkdownEscape, "\\*\\*\\*")
+		text, score, tags := ParseAnalysis(analysis)
+		sanitizedAnalysis := sanitizeAnalysis(text)
 
 		// Prioritize Gemini image URL, otherwise fall back to the first item's image
 		var bestImageURL string
@@ -81,61 +215,138 @@ func sendNotifications(telegramService *TelegramService, adminChatID, analysis,
 		} else if len(items) > 0 {
 			bestImageURL = items[0].ImageURL
 		}
+		sources := findSourceItems(geminiService, config, text, geminiImageURL, items)
 
+		// Rewrite the story once per language, not once per channel
+		texts := map[string]string{analysisLanguage: sanitizedAnalysis}
 		for _, channelID := range targetChannelIDs {
-			sendToChannel(telegramService, adminChatID, sanitizedAnalysis, bestImageURL, channelID, sourceName)
+			language := config.ChannelLanguages[channelID]
+			channelText, ok := texts[language]
+			if !ok && language == "" {
+				channelText, ok = sanitizedAnalysis, true
+			}
+			if !ok {
+				rewritten, err := rewritePost(geminiService, config, text, language)
+				if err != nil {
+					handleError(notifier, sourceName, err, fmt.Sprintf("rewriting in %s", language))
+					continue
+				}
+				notifier.Resolve(sourceName, fmt.Sprintf("rewriting in %s", language))
+				channelText = sanitizeAnalysis(rewritten)
+				texts[language] = channelText
+			}
+
+			post := Post{
+				SourceName: sourceName,
+				ChannelID:  channelID,
+				Text:       channelText,
+				PhotoURL:   bestImageURL,
+				Items:      items,
+				Sources:    sources,
+				Score:      score,
+				Tags:       tags,
+			}
+			if !followUpDetector.Check(&post) {
+				continue
+			}
+			if reviewService.RequiresReview(channelID) {
+				submitForReview(reviewService, notifier, post)
+				continue
+			}
+			publisher.Publish(post)
 		}
 	} else {
 		fmt.Printf("No significant news to report from %s.\n", sourceName)
-		telegramService.SendMessage(adminChatID, sourceName, fmt.Sprintf("No significant news to report from %s.", sourceName))
+		notifier.NoSignificantNews(sourceName)
 	}
 }
 
-// sendToChannel handles sending the news analysis to the appropriate channel.
-func sendToChannel(telegramService *TelegramService, adminChatID, message, photoURL, channelID, sourceName string) {
-	var err error
-	if photoURL != "" {
-		err = telegramService.SendPhoto(channelID, photoURL, sourceName, message)
-		if err != nil {
-			LogError("Failed to send photo, falling back to text message", err, "channel_id", channelID, "photo_url", photoURL)
-			telegramService.SendMessage(adminChatID, sourceName, fmt.Sprintf("Failed to send photo from %s to %s. Error: %v. Falling back to text.", sourceName, channelID, err))
-			// Fallback to sending the original full message as text
-			err = telegramService.SendMessage(channelID, sourceName, message)
+// findSourceItems determines which of the items the analysis was written from, so the post can link to them.
+func findSourceItems(geminiService *GeminiService, config *Config, analysis, geminiImageURL string, items []fetcher.NewsItem) []fetcher.NewsItem {
+	// The image Gemini picked identifies the article without another API call
+	if geminiImageURL != "" {
+		for _, item := range items {
+			if item.ImageURL == geminiImageURL {
+				return []fetcher.NewsItem{item}
+			}
 		}
-	} else {
-		err = telegramService.SendMessage(channelID, sourceName, message)
+	}
+	if len(items) == 1 {
+		return items
 	}
 
+	indices, err := geminiService.IdentifySources(analysis, items, config.RetryAttempts, config.RetryDelay)
 	if err != nil {
-		LogError("Failed to send final message to Telegram channel", err, "channel_id", channelID, "source", sourceName)
-		telegramService.SendMessage(adminChatID, sourceName, fmt.Sprintf("Failed to send news from %s to %s: %v", sourceName, channelID, err))
-	} else {
-		notification := fmt.Sprintf("News posted to %s from %s", channelID, sourceName)
-		if photoURL != "" {
-			notification += " (with photo)"
-		}
-		LogInfo("News posted successfully", "channel_id", channelID, "source", sourceName)
-		telegramService.SendMessage(adminChatID, sourceName, notification)
+		LogError("Failed to identify source articles, posting without links", err)
+		return nil
 	}
+
+	sources := make([]fetcher.NewsItem, 0, len(indices))
+	for _, index := range indices {
+		sources = append(sources, items[index])
+	}
+	return sources
+}
+
+// rewritePost rewrites the post text in another language.
+func rewritePost(geminiService *GeminiService, config *Config, text, language string) (string, error) {
+	LogInfo("Rewriting post", "language", language)
+	rewritten, err := geminiService.RewritePost(text, language, config.RetryAttempts, config.RetryDelay)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(rewritten) == "" {
+		return "", fmt.Errorf("gemini returned an empty rewrite")
+	}
+	return rewritten, nil
+}
+
+// sanitizeAnalysis escapes sequences in the model output that Telegram would misinterpret.
+func sanitizeAnalysis(analysis string) string {
+	return strings.ReplaceAll(analysis, TelegramMarkdownEscape, "\\*\\*\\*")
+}
+
+// submitForReview sends a candidate post to the admin chat instead of publishing it directly.
+func submitForReview(reviewService *ReviewService, notifier *AdminNotifier, post Post) {
+	err := reviewService.Submit(PendingPost{Post: post})
+	if err != nil {
+		LogError("Failed to submit post for review", err, "channel_id", post.ChannelID, "source", post.SourceName)
+		notifier.Error(post.SourceName, "submitting for review", err)
+		return
+	}
+	notifier.Resolve(post.SourceName, "submitting for review")
 }
 
-// handleError logs and sends an error message about a failed operation.
-func handleError(telegramService *TelegramService, adminChatID, sourceName string, err error, operation string) {
+// handleError logs and reports an error about a failed operation.
+func handleError(notifier *AdminNotifier, sourceName string, err error, operation string) {
 	LogError("Operation failed", err, "operation", operation, "source", sourceName)
-	errorMsg := fmt.Sprintf("Error %s from %s: %v", operation, sourceName, err)
-	telegramService.SendMessage(adminChatID, sourceName, errorMsg)
+	notifier.Error(sourceName, operation, err)
 }
 
 // handleNoNews handles the case when no news items are found.
-func handleNoNews(telegramService *TelegramService, adminChatID, sourceName string) {
+func handleNoNews(notifier *AdminNotifier, sourceName string) {
 	fmt.Printf("No new items from %s.\n", sourceName)
-	telegramService.SendMessage(adminChatID, sourceName, fmt.Sprintf("No new items from %s.", sourceName))
+	notifier.NoItems(sourceName)
 }
 
 func main() {
 	// Initialize structured logging
 	initLogger()
 	LogInfo("Starting NoNoise news fetcher", "version", "1.0.0")
+
+	// Setting up sources needs neither API keys nor channels, which may not be configured yet
+	if isSourcesSetupCommand(os.Args[1:]) {
+		config, err := LoadSourcesConfig()
+		if err != nil {
+			LogError("Failed to load sources", err)
+			log.Fatalf("Failed to load sources: %v", err)
+		}
+		if err := runSourcesCommand(os.Args[2:], nil, config); err != nil {
+			LogError("Command failed", err, "command", os.Args[1])
+			log.Fatalf("%v", err)
+		}
+		return
+	}
 	
 	config, err := LoadConfig()
 	if err != nil {
@@ -143,40 +354,107 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 	
-	geminiService := NewGeminiService(config.GeminiAPIKey, config.GeminiPrompt)
+	fetcher.SetPoliteness(fetcher.NewPoliteness(fetcher.PolitenessOptions{
+		Interval:        config.HostRequestInterval,
+		MaxCrawlDelay:   config.MaxCrawlDelay,
+		MaxConcurrency:  config.HostMaxConcurrency,
+		RespectRobots:   config.RespectRobots,
+		RobotsCacheFile: filepath.Join(config.StateDir, "robots.json"),
+		RobotsTTL:       config.RobotsCacheTTL,
+		RobotsAgent:     config.RobotsAgent,
+	}))
+
+	geminiService := NewGeminiService(config.GeminiAPIKey)
 	defer geminiService.Close()
-	telegramService := NewTelegramService(config.TelegramAPIKey, config.TargetChannels)
+	telegramService := NewTelegramService(config.TelegramAPIKey)
+	notifier := NewAdminNotifier(telegramService, config)
+	renderer, err := NewPostRenderer(config)
+	if err != nil {
+		LogError("Failed to load post templates", err)
+		log.Fatalf("Failed to load post templates: %v", err)
+	}
+	archive := NewPostArchive(telegramService, renderer, config)
+	promptBuilder, err := NewPromptBuilder(archive, config)
+	if err != nil {
+		LogError("Failed to load prompts", err)
+		log.Fatalf("Failed to load prompts: %v", err)
+	}
+	scheduler := NewScheduler(config)
+	publisher := NewPublisher(telegramService, archive, scheduler, renderer, notifier)
+	reviewService := NewReviewService(telegramService, geminiService, promptBuilder, publisher, config)
+	followUpDetector := NewFollowUpDetector(geminiService, archive, notifier, config)
+	digestService := NewDigestService(geminiService, publisher, notifier, config)
+	health := NewHealthTracker(notifier, config)
+
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:], reviewService, publisher, archive, health, config); err != nil {
+			LogError("Command failed", err, "command", os.Args[1])
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
+	// Deliver posts held back by quiet hours or publishing slots
+	if err := publisher.PublishDue(); err != nil {
+		LogError("Failed to publish queued posts", err)
+	}
+
+	// Apply editor decisions made since the last run before producing new candidates. Telegram
+	// allows one poller per bot, so a running review loop is left to receive them.
+	if len(config.ReviewChannels) > 0 {
+		if !config.ReviewLoop {
+			if err := reviewService.ProcessUpdates(0); err != nil {
+				LogError("Failed to process review updates", err)
+			}
+		}
+		if err := reviewService.ExpirePending(); err != nil {
+			LogError("Failed to expire pending posts", err)
+		}
+	}
 
 	// Process each news source from configuration
-	for sourceName, sourceURL := range config.NewsSources {
-		var fetcherObj fetcher.Fetcher
-		
-		// Create appropriate fetcher based on source
-		if sourceName == SVTVSourceName {
-			fetcherObj = &fetcher.SvtvFetcher{URL: sourceURL}
-		} else {
-			fetcherObj = &fetcher.GenericFetcher{URL: sourceURL}
+	for sourceName, source := range config.Sources {
+		if due, reason := health.ShouldFetch(sourceName); !due {
+			LogInfo("Skipping source", "source", sourceName, "reason", reason)
+			continue
+		}
+
+		fetcherObj, err := newFetcher(source)
+		if err != nil {
+			LogError("Failed to create fetcher", err, "source", sourceName)
+			continue
 		}
 		
-		// Get the target channel for this source
-		targetChannel, exists := config.TargetChannels[sourceName]
+		// Get the target channels for this source
+		channelIDs, exists := config.TargetChannels[sourceName]
 		if !exists {
 			LogError("No target channel configured for source", nil, "source", sourceName)
 			continue
 		}
 		
-		// Use the target channel for news, admin chat ID for notifications
-		channelIDs := []string{targetChannel}
-		
 		processNewsSource(
 			fetcherObj,
 			geminiService,
-			telegramService,
+			promptBuilder,
+			reviewService,
+			followUpDetector,
+			digestService,
+			publisher,
+			health,
+			notifier,
 			config,
-			sourceName,
+			source,
 			channelIDs,
 		)
 	}
 	
+	if err := digestService.PublishDue(); err != nil {
+		LogError("Failed to publish channel digests", err)
+	}
+
+	if err := notifier.SendDigestIfDue(); err != nil {
+		LogError("Failed to send admin digest", err)
+	}
+
 	LogInfo("News fetching completed for all sources")
 }
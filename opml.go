@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// opmlDocument is an OPML 2.0 subscription list as exchanged by feed readers.
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title,omitempty"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []*opmlOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+// opmlOutline is a feed, if XMLURL is set, or a category of feeds.
+type opmlOutline struct {
+	Text     string         `xml:"text,attr"`
+	Title    string         `xml:"title,attr,omitempty"`
+	Type     string         `xml:"type,attr,omitempty"`
+	XMLURL   string         `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string         `xml:"htmlUrl,attr,omitempty"`
+	Outlines []*opmlOutline `xml:"outline"`
+}
+
+// isChannelID reports whether a category name is itself a Telegram channel ID.
+func isChannelID(name string) bool {
+	return strings.HasPrefix(name, "@") || strings.HasPrefix(name, "-100")
+}
+
+// importOPML reads the feeds of an OPML document as sources. Feeds in a category are published to
+// the channel categoryChannels maps the category to, or to the category itself if its name is a
+// channel ID; nested categories inherit the channel of the enclosing one.
+func importOPML(r io.Reader, categoryChannels map[string]string) ([]*SourceConfig, error) {
+	var doc opmlDocument
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode OPML: %w", err)
+	}
+
+	var sources []*SourceConfig
+	var walk func(outlines []*opmlOutline, channel string)
+	walk = func(outlines []*opmlOutline, channel string) {
+		for _, outline := range outlines {
+			name := strings.TrimSpace(outline.Title)
+			if name == "" {
+				name = strings.TrimSpace(outline.Text)
+			}
+			if outline.XMLURL == "" {
+				categoryChannel := channel
+				if mapped, ok := categoryChannels[name]; ok {
+					categoryChannel = mapped
+				} else if isChannelID(name) {
+					categoryChannel = name
+				}
+				walk(outline.Outlines, categoryChannel)
+				continue
+			}
+			if name == "" {
+				name = outline.XMLURL
+			}
+			source := &SourceConfig{Name: name, URL: strings.TrimSpace(outline.XMLURL)}
+			if channel != "" {
+				source.Channels = []string{channel}
+			}
+			sources = append(sources, source)
+		}
+	}
+	walk(doc.Body.Outlines, "")
+	return sources, nil
+}
+
+// exportOPML writes the feed sources as an OPML document with a category per target channel.
+// Sources of other types have no place in feed readers and are left out; their names are returned.
+func exportOPML(w io.Writer, sources map[string]*SourceConfig, targetChannels map[string][]string) ([]string, error) {
+	categories := make(map[string]*opmlOutline)
+	var doc opmlDocument
+	doc.Version = "2.0"
+	doc.Head.Title = "NoNoise sources"
+	doc.Head.DateCreated = time.Now().Format(time.RFC1123Z)
+
+	var names, skipped []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		source := sources[name]
+		if source.Type != "" && source.Type != SourceTypeRSS {
+			skipped = append(skipped, name)
+			continue
+		}
+		feed := &opmlOutline{Text: name, Title: name, Type: "rss", XMLURL: source.URL}
+		channels := targetChannels[name]
+		if len(channels) == 0 {
+			doc.Body.Outlines = append(doc.Body.Outlines, feed)
+			continue
+		}
+		for _, channel := range channels {
+			category, ok := categories[channel]
+			if !ok {
+				category = &opmlOutline{Text: channel, Title: channel}
+				categories[channel] = category
+				doc.Body.Outlines = append(doc.Body.Outlines, category)
+			}
+			category.Outlines = append(category.Outlines, feed)
+		}
+	}
+
+	// Categories first, in channel order, then the feeds without a channel
+	slices.SortStableFunc(doc.Body.Outlines, func(a, b *opmlOutline) int {
+		if (a.XMLURL == "") != (b.XMLURL == "") {
+			if a.XMLURL == "" {
+				return -1
+			}
+			return 1
+		}
+		if a.XMLURL == "" {
+			return strings.Compare(a.Text, b.Text)
+		}
+		return 0
+	})
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode OPML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return skipped, err
+}
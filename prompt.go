@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"news/fetcher"
+)
+
+// PromptItem is a news item as seen by analysis prompt templates.
+type PromptItem struct {
+	Title       string
+	Link        string
+	PublishedOn time.Time
+	Source      string
+	Categories  []string
+	Authors     []string
+	// Content is the item text without HTML; RawContent keeps the markup.
+	Content    string
+	RawContent string
+	ImageURL   string
+	// Score, Comments and CommentsURL are the community signals of sites such as Reddit and
+	// Hacker News; zero or empty elsewhere.
+	Score       int
+	Comments    int
+	CommentsURL string
+}
+
+// PromptPost is a recently published post as seen by analysis prompt templates.
+type PromptPost struct {
+	MessageID int
+	PostedAt  time.Time
+	Text      string
+}
+
+// PromptData is the data available to analysis prompt templates.
+type PromptData struct {
+	Items []PromptItem
+	// Date is the current time.
+	Date       time.Time
+	SourceName string
+	ChannelID  string
+	// Language is the output language configured for the channel in CHANNEL_LANGUAGES, if any.
+	Language string
+	// RecentPosts are the channel's posts from the follow-up window, oldest first.
+	RecentPosts []PromptPost
+}
+
+// Prompt formats selectable with PROMPT_FORMAT.
+const (
+	PromptFormatLegacy   = "legacy"
+	PromptFormatTemplate = "template"
+)
+
+// analysisPrompt is a parsed analysis prompt: a template, or a legacy fmt-style prompt whose
+// single %s receives the formatted news.
+type analysisPrompt struct {
+	legacy string
+	tmpl   *template.Template
+}
+
+// PromptBuilder renders the analysis prompt for a source from the configured templates.
+type PromptBuilder struct {
+	defaultPrompt *analysisPrompt
+	sourcePrompts map[string]*analysisPrompt
+	archive       *PostArchive
+	config        *Config
+}
+
+// NewPromptBuilder creates a new PromptBuilder from the configured default and per-source prompts.
+func NewPromptBuilder(archive *PostArchive, config *Config) (*PromptBuilder, error) {
+	text := config.GeminiPrompt
+	if config.GeminiPromptFile != "" {
+		content, err := os.ReadFile(config.GeminiPromptFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GEMINI_PROMPT_FILE: %w", err)
+		}
+		text = string(content)
+	}
+	defaultPrompt, err := parseAnalysisPrompt("prompt", text, config.PromptFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GEMINI_PROMPT: %w", err)
+	}
+
+	sourcePrompts := make(map[string]*analysisPrompt)
+	for sourceName, path := range config.SourcePrompts {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt for %s: %w", sourceName, err)
+		}
+		prompt, err := parseAnalysisPrompt(path, string(content), config.PromptFormat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt for %s: %w", sourceName, err)
+		}
+		sourcePrompts[sourceName] = prompt
+	}
+
+	return &PromptBuilder{
+		defaultPrompt: defaultPrompt,
+		sourcePrompts: sourcePrompts,
+		archive:       archive,
+		config:        config,
+	}, nil
+}
+
+// prompt returns the prompt configured for the source.
+func (b *PromptBuilder) prompt(sourceName string) *analysisPrompt {
+	if prompt, ok := b.sourcePrompts[sourceName]; ok {
+		return prompt
+	}
+	return b.defaultPrompt
+}
+
+// PromptLanguageChannel declares that a prompt writes in the language of the channel it is
+// rendered for, as templates using {{.Language}} do.
+const PromptLanguageChannel = "channel"
+
+// Language returns the language the source's prompt writes posts for the channel in, as declared
+// by the source's prompt_language or PROMPT_LANGUAGE, or an empty string if it is not declared.
+func (b *PromptBuilder) Language(sourceName, channelID string) string {
+	language := b.config.PromptLanguage
+	if source, ok := b.config.Sources[sourceName]; ok && source.PromptLanguage != "" {
+		language = source.PromptLanguage
+	}
+	if language == PromptLanguageChannel {
+		return b.config.ChannelLanguages[channelID]
+	}
+	return language
+}
+
+// parseAnalysisPrompt parses a prompt in the given format, templates with the helper functions available.
+func parseAnalysisPrompt(name, text, format string) (*analysisPrompt, error) {
+	if format != PromptFormatTemplate {
+		return &analysisPrompt{legacy: text}, nil
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"truncate": truncateText,
+		"join":     func(sep string, values []string) string { return strings.Join(values, sep) },
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// A template of plain text would send Gemini the prompt without any news
+	if !slices.ContainsFunc(tmpl.Tree.Root.Nodes, func(node parse.Node) bool { return node.Type() != parse.NodeText }) {
+		return nil, fmt.Errorf("prompt has no template actions; set PROMPT_FORMAT=%s for prompts with %%s", PromptFormatLegacy)
+	}
+	return &analysisPrompt{tmpl: tmpl}, nil
+}
+
+// Build renders the analysis prompt of the source for the items, to be published to the channel.
+func (b *PromptBuilder) Build(sourceName, channelID string, items []fetcher.NewsItem) (string, error) {
+	prompt := b.prompt(sourceName)
+	if prompt.tmpl == nil {
+		return formatLegacyPrompt(prompt.legacy, items), nil
+	}
+
+	data := PromptData{
+		Date:       time.Now(),
+		SourceName: sourceName,
+		ChannelID:  channelID,
+		Language:   b.config.ChannelLanguages[channelID],
+	}
+	for _, item := range items {
+		source := item.SourceName
+		if source == "" {
+			source = sourceName
+		}
+		data.Items = append(data.Items, PromptItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			PublishedOn: item.PublishedOn,
+			Source:      source,
+			Categories:  item.Categories,
+			Authors:     item.Authors,
+			Content:     strings.TrimSpace(item.Content),
+			RawContent:  item.RawContent,
+			ImageURL:    item.ImageURL,
+			Score:       item.Score,
+			Comments:    item.Comments,
+			CommentsURL: item.CommentsURL,
+		})
+	}
+
+	recentPosts, err := b.archive.Recent(channelID, time.Now().Add(-b.config.FollowUpWindow))
+	if err != nil {
+		LogError("Failed to load recent posts for the prompt", err, "channel_id", channelID)
+	}
+	for _, post := range recentPosts {
+		data.RecentPosts = append(data.RecentPosts, PromptPost{MessageID: post.MessageID, PostedAt: post.PostedAt, Text: post.Text})
+	}
+
+	var buf bytes.Buffer
+	if err := prompt.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// formatLegacyPrompt substitutes the formatted news for the first %s of a fmt-style prompt.
+// Other text, including stray % signs, is kept as is, apart from %% which becomes %.
+func formatLegacyPrompt(prompt string, items []fetcher.NewsItem) string {
+	var newsContent strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&newsContent, "Title: %s\n", item.Title)
+		if item.ImageURL != "" {
+			fmt.Fprintf(&newsContent, "Image: %s\n", item.ImageURL)
+		}
+		if item.Score != 0 || item.Comments != 0 {
+			fmt.Fprintf(&newsContent, "Score: %d, comments: %d\n", item.Score, item.Comments)
+		}
+		fmt.Fprintf(&newsContent, "Content: %s\n\n", item.RawContent)
+	}
+
+	before, after, found := strings.Cut(prompt, "%s")
+	before = strings.ReplaceAll(before, "%%", "%")
+	if !found {
+		return before + "\n\n" + newsContent.String()
+	}
+	return before + newsContent.String() + strings.ReplaceAll(after, "%%", "%")
+}
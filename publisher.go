@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+
+	"news/fetcher"
+)
+
+// Post is a story ready to be published to a channel.
+type Post struct {
+	SourceName string             `json:"source_name"`
+	ChannelID  string             `json:"channel_id"`
+	Text       string             `json:"text"`
+	PhotoURL   string             `json:"photo_url,omitempty"`
+	Items      []fetcher.NewsItem `json:"items"`
+	// Sources are the items the post is actually based on.
+	Sources []fetcher.NewsItem `json:"sources,omitempty"`
+	// Score and Tags are the significance score and topic tags reported by the analyzer.
+	Score int      `json:"score,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// ReplyToMessageID makes the post a reply to an earlier post in the same channel.
+	ReplyToMessageID int `json:"reply_to_message_id,omitempty"`
+}
+
+// Links returns the links of the articles the post is based on.
+func (p Post) Links() []string {
+	var links []string
+	for _, item := range p.Sources {
+		if item.Link != "" {
+			links = append(links, item.Link)
+		}
+	}
+	return links
+}
+
+// Publisher delivers posts to their channels according to their schedules and records them in the archive.
+type Publisher struct {
+	telegramService *TelegramService
+	archive         *PostArchive
+	scheduler       *Scheduler
+	renderer        *PostRenderer
+	notifier        *AdminNotifier
+}
+
+// NewPublisher creates a new Publisher.
+func NewPublisher(telegramService *TelegramService, archive *PostArchive, scheduler *Scheduler, renderer *PostRenderer, notifier *AdminNotifier) *Publisher {
+	return &Publisher{
+		telegramService: telegramService,
+		archive:         archive,
+		scheduler:       scheduler,
+		renderer:        renderer,
+		notifier:        notifier,
+	}
+}
+
+// Publish delivers the post now, silently during quiet hours if configured so,
+// or queues it until the channel's schedule allows it. It fails if the post could not be sent;
+// the failure is also reported to the admin chat.
+func (p *Publisher) Publish(post Post) error {
+	silent, queued, err := p.scheduler.Plan(post)
+	if err != nil {
+		LogError("Failed to schedule post, sending it now", err, "channel_id", post.ChannelID, "source", post.SourceName)
+	}
+	if queued {
+		p.notifier.Queued(post.SourceName, fmt.Sprintf("News from %s queued for %s according to its schedule", post.SourceName, post.ChannelID))
+		return nil
+	}
+	return p.deliver(post, silent)
+}
+
+// PublishDue delivers queued posts whose time has come. Posts that cannot be sent stay in the
+// queue for the next run.
+func (p *Publisher) PublishDue() error {
+	due, err := p.scheduler.TakeDue()
+	var failed []*QueuedPost
+	for _, queued := range due {
+		LogInfo("Publishing queued post", "channel_id", queued.ChannelID, "source", queued.SourceName, "queued_at", queued.QueuedAt)
+		if p.deliver(queued.Post, queued.Silent) != nil {
+			failed = append(failed, queued)
+		}
+	}
+	if len(failed) > 0 {
+		if requeueErr := p.scheduler.Requeue(failed); requeueErr != nil && err == nil {
+			err = requeueErr
+		}
+	}
+	return err
+}
+
+// deliver sends the post to its channel, falling back to a text message if the photo cannot be sent.
+func (p *Publisher) deliver(post Post, silent bool) error {
+	opts := SendOptions{
+		ReplyToMessageID:    post.ReplyToMessageID,
+		DisableNotification: silent,
+		ReplyMarkup:         p.renderer.ReplyMarkup(post),
+	}
+
+	text, err := p.renderer.Render(post)
+	if err != nil {
+		LogError("Failed to render post, sending the analysis as is", err, "channel_id", post.ChannelID, "source", post.SourceName)
+		p.notifier.Error(post.SourceName, "rendering post", err)
+		text = post.Text
+	}
+
+	var msg *Message
+	if post.PhotoURL != "" {
+		photoOperation := fmt.Sprintf("sending photo to %s", post.ChannelID)
+		msg, err = p.telegramService.SendPhotoWithOptions(post.ChannelID, post.PhotoURL, text, opts)
+		if err == nil {
+			p.notifier.Resolve(post.SourceName, photoOperation)
+		} else {
+			LogError("Failed to send photo, falling back to text message", err, "channel_id", post.ChannelID, "photo_url", post.PhotoURL)
+			p.notifier.Error(post.SourceName, photoOperation, fmt.Errorf("%w (falling back to text)", err))
+			// Fallback to sending the original full message as text
+			msg, err = p.telegramService.SendMessageWithOptions(post.ChannelID, text, opts)
+		}
+	} else {
+		msg, err = p.telegramService.SendMessageWithOptions(post.ChannelID, text, opts)
+	}
+
+	postOperation := fmt.Sprintf("posting to %s", post.ChannelID)
+	if err != nil {
+		LogError("Failed to send final message to Telegram channel", err, "channel_id", post.ChannelID, "source", post.SourceName)
+		p.notifier.Error(post.SourceName, postOperation, err)
+		return err
+	}
+
+	p.notifier.Resolve(post.SourceName, postOperation)
+	if err := p.archive.Record(post, msg); err != nil {
+		LogError("Failed to archive post", err, "channel_id", post.ChannelID, "message_id", msg.MessageID)
+	}
+
+	notification := fmt.Sprintf("News posted to %s from %s", post.ChannelID, post.SourceName)
+	if post.PhotoURL != "" {
+		notification += " (with photo)"
+	}
+	if post.ReplyToMessageID != 0 {
+		notification += fmt.Sprintf(" as a follow-up to message %d", post.ReplyToMessageID)
+	}
+	if silent {
+		notification += " silently"
+	}
+	LogInfo("News posted successfully", "channel_id", post.ChannelID, "source", post.SourceName, "message_id", msg.MessageID)
+	p.notifier.Posted(post.SourceName, notification)
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// PostData is the data available to post templates.
+type PostData struct {
+	// Text is the complete post written by the analyzer.
+	Text string
+	// Headline and Body split Text into its bold first line and the rest.
+	Headline string
+	Body     string
+	// Score and Tags are the significance score and topic tags reported by the analyzer, if any.
+	Score int
+	Tags  []string
+	// SourceName is the configured name of the news source.
+	SourceName string
+	// Links are the links of the articles the post is based on; Link is the first of them.
+	Links []string
+	Link  string
+	// PublishedOn and Authors are the publication time and authors of the first article the post is based on.
+	PublishedOn time.Time
+	Authors     []string
+	// ChannelID is the channel the post is published to.
+	ChannelID string
+	// Identifier is the channel ID if the channel is configured for the source in TARGET_CHANNELS,
+	// and empty for posts such as digests.
+	Identifier string
+}
+
+// PostRenderer turns posts into the final message text using Go text/templates.
+type PostRenderer struct {
+	defaultTemplate  *template.Template
+	channelTemplates map[string]*template.Template
+	targetChannels   map[string][]string
+	readMoreButton   string
+}
+
+// NewPostRenderer creates a new PostRenderer from the configured default and per-channel templates.
+func NewPostRenderer(config *Config) (*PostRenderer, error) {
+	text := config.PostTemplate
+	if text == "" {
+		text = DefaultPostTemplate
+	}
+	defaultTemplate, err := parsePostTemplate("post", text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid POST_TEMPLATE: %w", err)
+	}
+
+	channelTemplates := make(map[string]*template.Template)
+	for channelID, path := range config.PostTemplates {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read post template for %s: %w", channelID, err)
+		}
+		tmpl, err := parsePostTemplate(path, string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid post template for %s: %w", channelID, err)
+		}
+		channelTemplates[channelID] = tmpl
+	}
+
+	return &PostRenderer{
+		defaultTemplate:  defaultTemplate,
+		channelTemplates: channelTemplates,
+		targetChannels:   config.TargetChannels,
+		readMoreButton:   config.ReadMoreButton,
+	}, nil
+}
+
+// parsePostTemplate parses a post template with the helper functions available.
+func parsePostTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"escape":   html.EscapeString,
+		"truncate": truncateText,
+		"hashtag":  hashtag,
+		"hashtags": hashtags,
+		"domain":   domain,
+		"join":     func(sep string, values []string) string { return strings.Join(values, sep) },
+	}).Parse(text)
+}
+
+// Render executes the channel's post template for the post.
+func (r *PostRenderer) Render(post Post) (string, error) {
+	headline, body := splitHeadline(post.Text)
+	data := PostData{
+		Text:       post.Text,
+		Headline:   headline,
+		Body:       body,
+		Score:      post.Score,
+		Tags:       post.Tags,
+		SourceName: post.SourceName,
+		Links:      post.Links(),
+		ChannelID:  post.ChannelID,
+	}
+	if slices.Contains(r.targetChannels[post.SourceName], post.ChannelID) {
+		data.Identifier = post.ChannelID
+	}
+	if len(data.Links) > 0 {
+		data.Link = data.Links[0]
+	}
+	if len(post.Sources) > 0 {
+		data.PublishedOn = post.Sources[0].PublishedOn
+		data.Authors = post.Sources[0].Authors
+	}
+
+	tmpl, ok := r.channelTemplates[post.ChannelID]
+	if !ok {
+		tmpl = r.defaultTemplate
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render post: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// ReplyMarkup returns the "read more" button linking to the post's first source, if enabled.
+func (r *PostRenderer) ReplyMarkup(post Post) *InlineKeyboardMarkup {
+	links := post.Links()
+	if r.readMoreButton == "" || len(links) == 0 {
+		return nil
+	}
+	return &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{
+		{{Text: r.readMoreButton, URL: links[0]}},
+	}}
+}
+
+var (
+	headlineRe = regexp.MustCompile(`(?s)^\s*<b>(.*?)</b>\s*`)
+	tagRe      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// splitHeadline splits a post into its bold headline, without tags, and the remaining body.
+// Posts that do not start with a bold headline have an empty headline.
+func splitHeadline(text string) (string, string) {
+	match := headlineRe.FindStringSubmatchIndex(text)
+	if match == nil {
+		return "", strings.TrimSpace(text)
+	}
+	headline := tagRe.ReplaceAllString(text[match[2]:match[3]], "")
+	return strings.TrimSpace(headline), strings.TrimSpace(text[match[1]:])
+}
+
+// truncateText shortens s to at most limit runes, ending it with an ellipsis if it was cut.
+func truncateText(limit int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	if limit < 1 {
+		return ""
+	}
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+// hashtag turns a phrase into a Telegram hashtag, e.g. "climate change" into "#climate_change".
+func hashtag(s string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if b.Len() > 0 {
+			b.WriteString("_")
+		}
+		b.WriteString(word)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "#" + b.String()
+}
+
+// hashtags turns a list of tags into space-separated hashtags.
+func hashtags(tags []string) string {
+	var result []string
+	for _, tag := range tags {
+		if h := hashtag(tag); h != "" {
+			result = append(result, h)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// domain returns the host name of a URL without the "www." prefix.
+func domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"news/store"
+)
+
+// Review actions encoded in the callback data of the inline keyboard.
+const (
+	reviewActionApprove    = "approve"
+	reviewActionReject     = "reject"
+	reviewActionEdit       = "edit"
+	reviewActionRegenerate = "regen"
+)
+
+// PendingPost is a candidate post waiting for an editor's decision.
+type PendingPost struct {
+	ID string `json:"id"`
+	Post
+	ReviewMessageID     int       `json:"review_message_id"`
+	EditPromptMessageID int       `json:"edit_prompt_message_id,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// reviewState is the persisted state of the review workflow.
+type reviewState struct {
+	UpdateOffset int                     `json:"update_offset"`
+	Pending      map[string]*PendingPost `json:"pending"`
+}
+
+// ReviewService routes candidate posts through the admin chat for approval before publishing.
+type ReviewService struct {
+	store           *store.JSONFile[reviewState]
+	telegramService *TelegramService
+	geminiService   *GeminiService
+	promptBuilder   *PromptBuilder
+	publisher       *Publisher
+	config          *Config
+}
+
+// NewReviewService creates a new ReviewService persisting its state in the configured state directory.
+func NewReviewService(telegramService *TelegramService, geminiService *GeminiService, promptBuilder *PromptBuilder, publisher *Publisher, config *Config) *ReviewService {
+	return &ReviewService{
+		store:           store.NewJSONFile[reviewState](filepath.Join(config.StateDir, "review.json")),
+		telegramService: telegramService,
+		geminiService:   geminiService,
+		promptBuilder:   promptBuilder,
+		publisher:       publisher,
+		config:          config,
+	}
+}
+
+// RequiresReview reports whether posts for the channel must be approved first.
+func (s *ReviewService) RequiresReview(channelID string) bool {
+	return s.config.ReviewChannels[channelID]
+}
+
+// Submit sends a candidate post to the admin chat with review buttons and stores it as pending.
+func (s *ReviewService) Submit(post PendingPost) error {
+	id, err := newPendingID()
+	if err != nil {
+		return err
+	}
+	post.ID = id
+	post.CreatedAt = time.Now()
+
+	msg, err := s.telegramService.SendMessageWithOptions(s.config.TelegramChatID, s.renderReview(&post), SendOptions{ReplyMarkup: reviewKeyboard(post.ID)})
+	if err != nil {
+		return fmt.Errorf("failed to send post for review: %w", err)
+	}
+	post.ReviewMessageID = msg.MessageID
+
+	err = s.store.Update(func(state *reviewState) error {
+		if state.Pending == nil {
+			state.Pending = make(map[string]*PendingPost)
+		}
+		state.Pending[post.ID] = &post
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store pending post: %w", err)
+	}
+
+	LogInfo("Post submitted for review", "id", post.ID, "channel_id", post.ChannelID, "source", post.SourceName)
+	return nil
+}
+
+// ProcessUpdates handles editor actions received since the last call.
+// A positive timeout long-polls Telegram for up to that many seconds.
+func (s *ReviewService) ProcessUpdates(timeout int) error {
+	state, err := s.store.Load()
+	if err != nil {
+		return err
+	}
+
+	updates, err := s.telegramService.GetUpdates(state.UpdateOffset, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to get updates: %w", err)
+	}
+
+	for _, update := range updates {
+		switch {
+		case update.CallbackQuery != nil:
+			s.handleCallback(update.CallbackQuery)
+		case update.Message != nil && update.Message.ReplyToMessage != nil:
+			s.handleEditReply(update.Message)
+		}
+
+		err := s.store.Update(func(state *reviewState) error {
+			state.UpdateOffset = update.UpdateID + 1
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpirePending drops pending posts older than the configured review expiry.
+func (s *ReviewService) ExpirePending() error {
+	var expired []*PendingPost
+	err := s.store.Update(func(state *reviewState) error {
+		for id, post := range state.Pending {
+			if time.Since(post.CreatedAt) > s.config.ReviewExpiry {
+				expired = append(expired, post)
+				delete(state.Pending, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, post := range expired {
+		LogInfo("Pending post expired", "id", post.ID, "channel_id", post.ChannelID)
+		s.closeReview(post, "⌛ Expired without a decision")
+	}
+	return nil
+}
+
+// handleCallback dispatches an inline button press to the matching review action.
+func (s *ReviewService) handleCallback(query *CallbackQuery) {
+	// Only editors in the admin chat decide on posts
+	if query.Message == nil || fmt.Sprint(query.Message.Chat.ID) != s.config.TelegramChatID {
+		s.telegramService.AnswerCallbackQuery(query.ID, "")
+		return
+	}
+	action, id, ok := strings.Cut(query.Data, ":")
+	if !ok {
+		return
+	}
+
+	post, err := s.takePending(id, action == reviewActionApprove || action == reviewActionReject)
+	if err != nil || post == nil {
+		s.telegramService.AnswerCallbackQuery(query.ID, "This post is no longer pending.")
+		return
+	}
+
+	editor := query.From.FirstName
+	if query.From.Username != "" {
+		editor = "@" + query.From.Username
+	}
+
+	switch action {
+	case reviewActionApprove:
+		s.telegramService.AnswerCallbackQuery(query.ID, "Publishing...")
+		LogInfo("Post approved", "id", post.ID, "channel_id", post.ChannelID, "editor", editor)
+		if err := s.publisher.Publish(post.Post); err != nil {
+			// Keep the post and its buttons so the editor can approve it again
+			LogError("Failed to publish approved post, keeping it pending", err, "id", post.ID)
+			s.restorePending(post)
+			return
+		}
+		s.closeReview(post, fmt.Sprintf("✅ Approved by %s", editor))
+	case reviewActionReject:
+		s.telegramService.AnswerCallbackQuery(query.ID, "Rejected.")
+		LogInfo("Post rejected", "id", post.ID, "channel_id", post.ChannelID, "editor", editor)
+		s.closeReview(post, fmt.Sprintf("❌ Rejected by %s", editor))
+	case reviewActionEdit:
+		s.telegramService.AnswerCallbackQuery(query.ID, "Reply with the new text.")
+		s.requestEdit(post)
+	case reviewActionRegenerate:
+		s.telegramService.AnswerCallbackQuery(query.ID, "Regenerating...")
+		s.regenerate(post, editor)
+	}
+}
+
+// handleEditReply replaces the text of a pending post with an editor's reply to the edit prompt.
+func (s *ReviewService) handleEditReply(msg *Message) {
+	if fmt.Sprint(msg.Chat.ID) != s.config.TelegramChatID || msg.Text == "" {
+		return
+	}
+
+	state, err := s.store.Load()
+	if err != nil {
+		LogError("Failed to load review state", err)
+		return
+	}
+	for _, post := range state.Pending {
+		if post.EditPromptMessageID != 0 && post.EditPromptMessageID == msg.ReplyToMessage.MessageID {
+			s.replaceCandidate(post, msg.Text, post.PhotoURL, "✏️ Edited")
+			return
+		}
+	}
+}
+
+// requestEdit asks the editor to reply with replacement text for the post.
+func (s *ReviewService) requestEdit(post *PendingPost) {
+	prompt := fmt.Sprintf("✏️ Reply to this message with the new text for post <code>%s</code> (HTML formatting is allowed).", post.ID)
+	msg, err := s.telegramService.SendForceReply(s.config.TelegramChatID, prompt)
+	if err != nil {
+		LogError("Failed to send edit prompt", err, "id", post.ID)
+		return
+	}
+
+	err = s.store.Update(func(state *reviewState) error {
+		if pending, ok := state.Pending[post.ID]; ok {
+			pending.EditPromptMessageID = msg.MessageID
+		}
+		return nil
+	})
+	if err != nil {
+		LogError("Failed to store edit prompt", err, "id", post.ID)
+	}
+}
+
+// regenerate asks Gemini for a fresh rewrite of the post's source items.
+func (s *ReviewService) regenerate(post *PendingPost, editor string) {
+	LogInfo("Regenerating post", "id", post.ID, "editor", editor)
+	prompt, err := s.promptBuilder.Build(post.SourceName, post.ChannelID, post.Items)
+	if err != nil {
+		LogError("Failed to build prompt", err, "id", post.ID)
+		s.telegramService.SendMessage(s.config.TelegramChatID, fmt.Sprintf("Failed to regenerate post %s: %v", post.ID, err))
+		return
+	}
+	imageURL, analysis, err := s.geminiService.AnalyzeNews(prompt, s.config.RetryAttempts, s.config.RetryDelay)
+	if err != nil {
+		LogError("Failed to regenerate post", err, "id", post.ID)
+		s.telegramService.SendMessage(s.config.TelegramChatID, fmt.Sprintf("Failed to regenerate post %s: %v", post.ID, err))
+		return
+	}
+	if strings.TrimSpace(analysis) == "" {
+		s.telegramService.SendMessage(s.config.TelegramChatID, fmt.Sprintf("Gemini found nothing significant when regenerating post %s; the current version is kept.", post.ID))
+		return
+	}
+
+	photoURL := post.PhotoURL
+	if imageURL != "" {
+		photoURL = imageURL
+	}
+	text, score, tags := ParseAnalysis(analysis)
+	post.Score, post.Tags = score, tags
+	if language := s.config.ChannelLanguages[post.ChannelID]; language != "" && language != s.promptBuilder.Language(post.SourceName, post.ChannelID) {
+		text, err = rewritePost(s.geminiService, s.config, text, language)
+		if err != nil {
+			LogError("Failed to rewrite regenerated post", err, "id", post.ID, "language", language)
+			s.telegramService.SendMessage(s.config.TelegramChatID, fmt.Sprintf("Failed to regenerate post %s: %v", post.ID, err))
+			return
+		}
+	}
+	s.replaceCandidate(post, sanitizeAnalysis(text), photoURL, fmt.Sprintf("🔄 Regenerated by %s", editor))
+}
+
+// replaceCandidate closes the current review message and submits an updated version of the post.
+func (s *ReviewService) replaceCandidate(post *PendingPost, text, photoURL, status string) {
+	if _, err := s.takePending(post.ID, true); err != nil {
+		LogError("Failed to update pending post", err, "id", post.ID)
+		return
+	}
+	s.closeReview(post, status)
+
+	updated := *post
+	updated.Text = text
+	updated.PhotoURL = photoURL
+	updated.EditPromptMessageID = 0
+	if err := s.Submit(updated); err != nil {
+		LogError("Failed to resubmit post for review", err, "id", post.ID)
+	}
+}
+
+// takePending returns the pending post with the given ID, removing it from the store if remove is set.
+func (s *ReviewService) takePending(id string, remove bool) (*PendingPost, error) {
+	var post *PendingPost
+	err := s.store.Update(func(state *reviewState) error {
+		post = state.Pending[id]
+		if remove {
+			delete(state.Pending, id)
+		}
+		return nil
+	})
+	return post, err
+}
+
+// restorePending puts a post taken from the store back, so it stays up for review.
+func (s *ReviewService) restorePending(post *PendingPost) {
+	err := s.store.Update(func(state *reviewState) error {
+		if state.Pending == nil {
+			state.Pending = make(map[string]*PendingPost)
+		}
+		state.Pending[post.ID] = post
+		return nil
+	})
+	if err != nil {
+		LogError("Failed to restore pending post", err, "id", post.ID)
+	}
+}
+
+// closeReview marks the review message with its final status and removes the buttons.
+func (s *ReviewService) closeReview(post *PendingPost, status string) {
+	text := s.renderReview(post) + "\n\n" + status
+	if err := s.telegramService.EditMessageText(s.config.TelegramChatID, post.ReviewMessageID, text, nil); err != nil {
+		LogError("Failed to update review message", err, "id", post.ID)
+	}
+}
+
+// renderReview formats a pending post for the admin chat.
+func (s *ReviewService) renderReview(post *PendingPost) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📝 <b>Review</b> <code>%s</code>: %s → %s\n", post.ID, html.EscapeString(post.SourceName), html.EscapeString(post.ChannelID))
+	fmt.Fprintf(&b, "Expires: %s\n", post.CreatedAt.Add(s.config.ReviewExpiry).Format("2006-01-02 15:04 MST"))
+	if post.PhotoURL != "" {
+		fmt.Fprintf(&b, "Photo: %s\n", html.EscapeString(post.PhotoURL))
+	}
+	if post.ReplyToMessageID != 0 {
+		fmt.Fprintf(&b, "Follow-up: will reply to message %d\n", post.ReplyToMessageID)
+	}
+	for _, link := range post.Links() {
+		fmt.Fprintf(&b, "Source: %s\n", html.EscapeString(link))
+	}
+	b.WriteString("\n")
+	b.WriteString(post.Text)
+	return b.String()
+}
+
+// reviewKeyboard builds the inline keyboard attached to review messages.
+func reviewKeyboard(id string) *InlineKeyboardMarkup {
+	button := func(text, action string) InlineKeyboardButton {
+		return InlineKeyboardButton{Text: text, CallbackData: action + ":" + id}
+	}
+	return &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{
+		{button("✅ Approve", reviewActionApprove), button("❌ Reject", reviewActionReject)},
+		{button("✏️ Edit", reviewActionEdit), button("🔄 Regenerate", reviewActionRegenerate)},
+	}}
+}
+
+// newPendingID generates a short random identifier for a pending post.
+func newPendingID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate pending post ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"news/store"
+)
+
+// Quiet hours modes.
+const (
+	QuietModeSilent = "silent" // deliver without a notification sound
+	QuietModeDefer  = "defer"  // hold posts until the quiet hours end
+)
+
+// ChannelSchedule describes when a channel may receive posts.
+type ChannelSchedule struct {
+	Location *time.Location
+	// QuietStart and QuietEnd are minutes since local midnight; equal values disable quiet hours.
+	QuietStart int
+	QuietEnd   int
+	QuietMode  string
+	// Slots are the minutes since local midnight at which queued posts are published.
+	// Without slots, posts are published as soon as they are ready.
+	Slots []int
+}
+
+// inQuietHours reports whether t falls within the quiet hours.
+func (c ChannelSchedule) inQuietHours(t time.Time) bool {
+	if c.QuietStart == c.QuietEnd {
+		return false
+	}
+	minute := minuteOfDay(t.In(c.Location))
+	if c.QuietStart < c.QuietEnd {
+		return minute >= c.QuietStart && minute < c.QuietEnd
+	}
+	// The window spans midnight, e.g. 23:00-07:00
+	return minute >= c.QuietStart || minute < c.QuietEnd
+}
+
+// nextAt returns the first time at or after t whose local time of day is minute.
+func (c ChannelSchedule) nextAt(t time.Time, minute int) time.Time {
+	local := t.In(c.Location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), minute/60, minute%60, 0, 0, c.Location)
+	if next.Before(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, minute/60, minute%60, 0, 0, c.Location)
+	}
+	return next
+}
+
+// nextSlot returns the first publishing slot at or after t, or t itself if the channel has no slots.
+func (c ChannelSchedule) nextSlot(t time.Time) time.Time {
+	if len(c.Slots) == 0 {
+		return t
+	}
+	var best time.Time
+	for _, slot := range c.Slots {
+		next := c.nextAt(t, slot)
+		if best.IsZero() || next.Before(best) {
+			best = next
+		}
+	}
+	return best
+}
+
+// Plan returns when a post that is ready at now should be delivered and whether it should be silent.
+func (c ChannelSchedule) Plan(now time.Time) (time.Time, bool) {
+	first := c.nextSlot(now)
+	sendAt := first
+	// Each iteration moves past one quiet window
+	for i := 0; i <= len(c.Slots) && c.inQuietHours(sendAt); i++ {
+		if c.QuietMode == QuietModeSilent {
+			return sendAt, true
+		}
+		sendAt = c.nextSlot(c.nextAt(sendAt, c.QuietEnd))
+	}
+	// If every slot is in quiet hours, give up and send silently at the first one
+	if c.inQuietHours(sendAt) {
+		return first, true
+	}
+	return sendAt, false
+}
+
+// QueuedPost is a post held back until its delivery time.
+type QueuedPost struct {
+	Post
+	SendAt   time.Time `json:"send_at"`
+	Silent   bool      `json:"silent,omitempty"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// scheduleState is the persisted delivery queue.
+type scheduleState struct {
+	Queue []*QueuedPost `json:"queue"`
+}
+
+// Scheduler applies per-channel quiet hours and publishing slots and keeps the queue of deferred posts.
+type Scheduler struct {
+	store     *store.JSONFile[scheduleState]
+	schedules map[string]ChannelSchedule
+}
+
+// NewScheduler creates a new Scheduler persisting its queue in the configured state directory.
+func NewScheduler(config *Config) *Scheduler {
+	return &Scheduler{
+		store:     store.NewJSONFile[scheduleState](filepath.Join(config.StateDir, "queue.json")),
+		schedules: config.ChannelSchedules,
+	}
+}
+
+// Plan decides when the post should be delivered. Posts due now are returned with queued set to false;
+// otherwise the post is stored in the queue until it is due.
+func (s *Scheduler) Plan(post Post) (silent bool, queued bool, err error) {
+	schedule, ok := s.schedules[post.ChannelID]
+	if !ok {
+		return false, false, nil
+	}
+
+	now := time.Now()
+	sendAt, silent := schedule.Plan(now)
+	if !sendAt.After(now) {
+		return silent, false, nil
+	}
+
+	err = s.store.Update(func(state *scheduleState) error {
+		state.Queue = append(state.Queue, &QueuedPost{Post: post, SendAt: sendAt, Silent: silent, QueuedAt: now})
+		return nil
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("failed to queue post: %w", err)
+	}
+
+	LogInfo("Post queued", "channel_id", post.ChannelID, "source", post.SourceName, "send_at", sendAt)
+	return silent, true, nil
+}
+
+// TakeDue removes and returns the queued posts whose delivery time has come, oldest first.
+func (s *Scheduler) TakeDue() ([]*QueuedPost, error) {
+	var due []*QueuedPost
+	err := s.store.Update(func(state *scheduleState) error {
+		now := time.Now()
+		kept := state.Queue[:0]
+		for _, queued := range state.Queue {
+			if queued.SendAt.After(now) {
+				kept = append(kept, queued)
+			} else {
+				due = append(due, queued)
+			}
+		}
+		state.Queue = kept
+		return nil
+	})
+
+	sort.Slice(due, func(i, j int) bool { return due[i].SendAt.Before(due[j].SendAt) })
+	return due, err
+}
+
+// Requeue puts posts taken from the queue back into it, to be sent on the next run.
+func (s *Scheduler) Requeue(posts []*QueuedPost) error {
+	return s.store.Update(func(state *scheduleState) error {
+		state.Queue = append(state.Queue, posts...)
+		return nil
+	})
+}
+
+// minuteOfDay returns the number of minutes since midnight of t.
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// parseClock parses a time of day in the "HH:MM" or "HH" format into minutes since midnight.
+func parseClock(value string) (int, error) {
+	hourStr, minuteStr, hasMinutes := strings.Cut(strings.TrimSpace(value), ":")
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	minute := 0
+	if hasMinutes {
+		minute, err = strconv.Atoi(minuteStr)
+		if err != nil || minute < 0 || minute > 59 {
+			return 0, fmt.Errorf("invalid time of day %q", value)
+		}
+	}
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChannelSchedulePlan(t *testing.T) {
+	location := time.FixedZone("UTC+3", 3*60*60)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, location)
+	}
+	night := ChannelSchedule{Location: location, QuietStart: 23 * 60, QuietEnd: 7 * 60, QuietMode: QuietModeDefer}
+	silentNight := night
+	silentNight.QuietMode = QuietModeSilent
+	lunch := ChannelSchedule{Location: location, QuietStart: 13 * 60, QuietEnd: 14 * 60, QuietMode: QuietModeDefer}
+	slots := ChannelSchedule{Location: location, Slots: []int{18 * 60, 9 * 60}}
+	slotsAtNight := ChannelSchedule{Location: location, QuietStart: 23 * 60, QuietEnd: 7 * 60, QuietMode: QuietModeDefer, Slots: []int{6 * 60, 12 * 60}}
+	onlyQuietSlots := ChannelSchedule{Location: location, QuietStart: 23 * 60, QuietEnd: 7 * 60, QuietMode: QuietModeDefer, Slots: []int{60, 2 * 60}}
+
+	tests := []struct {
+		name       string
+		schedule   ChannelSchedule
+		now        time.Time
+		wantSendAt time.Time
+		wantSilent bool
+	}{
+		{"no restrictions", ChannelSchedule{Location: location}, at(17, 3, 0), at(17, 3, 0), false},
+		{"outside quiet hours", night, at(17, 12, 0), at(17, 12, 0), false},
+		{"deferred past midnight", night, at(17, 23, 30), at(18, 7, 0), false},
+		{"deferred after midnight", night, at(18, 2, 0), at(18, 7, 0), false},
+		{"quiet hours start inclusive", night, at(17, 23, 0), at(18, 7, 0), false},
+		{"quiet hours end exclusive", night, at(18, 7, 0), at(18, 7, 0), false},
+		{"silent", silentNight, at(17, 23, 30), at(17, 23, 30), true},
+		{"daytime window", lunch, at(17, 13, 30), at(17, 14, 0), false},
+		{"quiet hours in another zone", night, time.Date(2026, 10, 17, 21, 0, 0, 0, time.UTC), at(18, 7, 0), false},
+		{"next slot", slots, at(17, 10, 0), at(17, 18, 0), false},
+		{"slot tomorrow", slots, at(17, 19, 0), at(18, 9, 0), false},
+		{"at a slot", slots, at(17, 9, 0), at(17, 9, 0), false},
+		{"slot in quiet hours", slotsAtNight, at(18, 0, 30), at(18, 12, 0), false},
+		{"every slot in quiet hours", onlyQuietSlots, at(17, 12, 0), at(18, 1, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sendAt, silent := tt.schedule.Plan(tt.now)
+			if !sendAt.Equal(tt.wantSendAt) || silent != tt.wantSilent {
+				t.Errorf("Plan(%v) = %v, %v, want %v, %v", tt.now, sendAt, silent, tt.wantSendAt, tt.wantSilent)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "07:30", want: 7*60 + 30},
+		{value: "7", want: 7 * 60},
+		{value: " 23:59 ", want: 23*60 + 59},
+		{value: "00:00", want: 0},
+		{value: "24:00", wantErr: true},
+		{value: "12:60", wantErr: true},
+		{value: "noon", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseClock(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClock(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseClock(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
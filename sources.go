@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"news/fetcher"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Source types selecting the fetcher.
+const (
+	SourceTypeRSS      = "rss"
+	SourceTypeHTML     = "html"
+	SourceTypeJSON     = "json"
+	SourceTypeTelegram = "telegram"
+	SourceTypeMastodon = "mastodon"
+	SourceTypeBluesky  = "bluesky"
+	SourceTypeReddit   = "reddit"
+	SourceTypeHN       = "hackernews"
+)
+
+// SourceConfig holds the settings of a single news source.
+type SourceConfig struct {
+	Name string `json:"name"`
+	// URL is the address of the feed, page or API, the channel of SourceTypeTelegram sources, the
+	// account or hashtag link of SourceTypeMastodon sources, the account of SourceTypeBluesky sources,
+	// the subreddit of SourceTypeReddit sources or the page of SourceTypeHN sources.
+	URL string `json:"url"`
+	// Channels are target channels in addition to those TARGET_CHANNELS lists for the source.
+	Channels []string `json:"channels,omitempty"`
+	// Type selects the fetcher; SourceTypeRSS by default.
+	Type string `json:"type,omitempty"`
+	// HTML locates the articles of SourceTypeHTML sources.
+	HTML *fetcher.HTMLSelectors `json:"html,omitempty"`
+	// JSON maps the response fields of SourceTypeJSON sources.
+	JSON *fetcher.JSONFields `json:"json,omitempty"`
+	// PromptLanguage overrides PROMPT_LANGUAGE for sources with their own prompt.
+	PromptLanguage string `json:"prompt_language,omitempty"`
+	// Filter drops items before analysis; nil keeps every item.
+	Filter *fetcher.Filter `json:"filter,omitempty"`
+	// DateLayouts are Go time layouts tried before the built-in ones for dates the feed library
+	// does not understand, and DateLocales restrict the languages of month and day names.
+	DateLayouts []string `json:"date_layouts,omitempty"`
+	DateLocales []string `json:"date_locales,omitempty"`
+	// Undated is the policy for items without a parseable publication date.
+	Undated string `json:"undated,omitempty"`
+	// Charset overrides the encoding declared by the server or the feed.
+	Charset string `json:"charset,omitempty"`
+	// MaxBodySize limits the size of fetched documents in bytes.
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+	// HTTP sets the proxy, headers, cookies, credentials, TLS settings and timeout of the requests.
+	HTTP *fetcher.HTTPOptions `json:"http,omitempty"`
+	// LookbackHours and OverlapHours override LOOKBACK_HOURS and WATERMARK_OVERLAP.
+	LookbackHours int `json:"lookback_hours,omitempty"`
+	OverlapHours  int `json:"overlap_hours,omitempty"`
+	// StaleAfterHours overrides SOURCE_STALE_AFTER for sources that publish rarely or often.
+	StaleAfterHours int `json:"stale_after_hours,omitempty"`
+}
+
+// locatingFeedOperation is the operation under which the admin is told a feed has moved.
+const locatingFeedOperation = "locating the feed"
+
+// isFeed reports whether the source is an RSS, Atom or JSON feed.
+func (s *SourceConfig) isFeed() bool {
+	return s.Type == "" || s.Type == SourceTypeRSS
+}
+
+// newFetcher creates the fetcher for a source.
+func newFetcher(source *SourceConfig) (fetcher.Fetcher, error) {
+	dates, err := fetcher.NewDateParser(source.DateLayouts, source.DateLocales)
+	if err != nil {
+		return nil, err
+	}
+	switch source.Type {
+	case SourceTypeHTML:
+		return &fetcher.HTMLFetcher{
+			URL:         source.URL,
+			SourceName:  source.Name,
+			Selectors:   *source.HTML,
+			Dates:       dates,
+			Undated:     source.Undated,
+			Charset:     source.Charset,
+			MaxBodySize: source.MaxBodySize,
+			HTTP:        source.HTTP,
+		}, nil
+	case SourceTypeJSON:
+		return &fetcher.JSONFetcher{
+			URL:         source.URL,
+			SourceName:  source.Name,
+			Fields:      *source.JSON,
+			Dates:       dates,
+			Undated:     source.Undated,
+			MaxBodySize: source.MaxBodySize,
+			HTTP:        source.HTTP,
+		}, nil
+	case SourceTypeTelegram:
+		return &fetcher.TelegramChannelFetcher{
+			Channel:     source.URL,
+			SourceName:  source.Name,
+			MaxBodySize: source.MaxBodySize,
+			HTTP:        source.HTTP,
+		}, nil
+	case SourceTypeMastodon:
+		return &fetcher.MastodonFetcher{
+			URL:         source.URL,
+			SourceName:  source.Name,
+			MaxBodySize: source.MaxBodySize,
+			HTTP:        source.HTTP,
+		}, nil
+	case SourceTypeBluesky:
+		return &fetcher.BlueskyFetcher{
+			Account:     source.URL,
+			SourceName:  source.Name,
+			MaxBodySize: source.MaxBodySize,
+			HTTP:        source.HTTP,
+		}, nil
+	case SourceTypeReddit:
+		return &fetcher.RedditFetcher{
+			Subreddit:   source.URL,
+			SourceName:  source.Name,
+			MaxBodySize: source.MaxBodySize,
+			HTTP:        source.HTTP,
+		}, nil
+	case SourceTypeHN:
+		return &fetcher.HackerNewsFetcher{
+			Page:        source.URL,
+			SourceName:  source.Name,
+			MaxBodySize: source.MaxBodySize,
+			HTTP:        source.HTTP,
+		}, nil
+	default:
+		return &fetcher.RSSFetcher{
+			URL:         source.URL,
+			SourceName:  source.Name,
+			Dates:       dates,
+			Undated:     source.Undated,
+			Charset:     source.Charset,
+			MaxBodySize: source.MaxBodySize,
+			HTTP:        source.HTTP,
+		}, nil
+	}
+}
+
+// loadSourcesFile reads the list of sources from a JSON file.
+func loadSourcesFile(path string) ([]*SourceConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sources file: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var sources []*SourceConfig
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("failed to decode sources file: %w", err)
+	}
+
+	for _, source := range sources {
+		if source.Name == "" || source.URL == "" {
+			return nil, fmt.Errorf("source without a name or URL in %s", path)
+		}
+		switch source.Type {
+		case "", SourceTypeRSS:
+		case SourceTypeHTML:
+			if source.HTML == nil {
+				return nil, fmt.Errorf("no html selectors for %s", source.Name)
+			}
+			if err := source.HTML.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid html selectors for %s: %w", source.Name, err)
+			}
+		case SourceTypeJSON:
+			if source.JSON == nil {
+				return nil, fmt.Errorf("no json fields for %s", source.Name)
+			}
+			if err := source.JSON.Compile(); err != nil {
+				return nil, fmt.Errorf("invalid json fields for %s: %w", source.Name, err)
+			}
+		case SourceTypeTelegram:
+			if _, err := fetcher.TelegramChannelURL(source.URL); err != nil {
+				return nil, fmt.Errorf("invalid telegram channel for %s: %w", source.Name, err)
+			}
+		case SourceTypeMastodon:
+			if _, err := fetcher.ParseMastodonURL(source.URL); err != nil {
+				return nil, fmt.Errorf("invalid mastodon timeline for %s: %w", source.Name, err)
+			}
+		case SourceTypeBluesky:
+			if _, err := fetcher.BlueskyActor(source.URL); err != nil {
+				return nil, fmt.Errorf("invalid bluesky account for %s: %w", source.Name, err)
+			}
+		case SourceTypeReddit:
+			if _, err := fetcher.RedditListingURL(source.URL); err != nil {
+				return nil, fmt.Errorf("invalid subreddit for %s: %w", source.Name, err)
+			}
+		case SourceTypeHN:
+			if _, err := fetcher.HackerNewsList(source.URL); err != nil {
+				return nil, fmt.Errorf("invalid hacker news page for %s: %w", source.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown type %q for %s", source.Type, source.Name)
+		}
+		switch source.Undated {
+		case "", fetcher.UndatedSkip, fetcher.UndatedFetchTime:
+		case fetcher.UndatedUpdated:
+			// Only feeds give items an update time
+			if !source.isFeed() {
+				return nil, fmt.Errorf("undated policy %q is only supported by rss sources, not %s", source.Undated, source.Name)
+			}
+		default:
+			return nil, fmt.Errorf("invalid undated policy %q for %s", source.Undated, source.Name)
+		}
+		if _, err := fetcher.NewDateParser(source.DateLayouts, source.DateLocales); err != nil {
+			return nil, fmt.Errorf("invalid date settings for %s: %w", source.Name, err)
+		}
+		if source.Charset != "" {
+			if encoding, _ := charset.Lookup(source.Charset); encoding == nil {
+				return nil, fmt.Errorf("unknown charset %q for %s", source.Charset, source.Name)
+			}
+		}
+		if source.HTTP != nil {
+			if err := source.HTTP.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid http options for %s: %w", source.Name, err)
+			}
+		}
+		if source.Filter != nil {
+			if err := source.Filter.Compile(); err != nil {
+				return nil, fmt.Errorf("invalid filter for %s: %w", source.Name, err)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// healFeed reads a feed that is gone from where it moved, so a site moving its feed does not
+// silence the source. A feed of the site is taken for the moved one only if it has the title or
+// items of the source's feed, and is remembered until the source's URL changes; if none does, the
+// site is searched again only after FeedRediscoveryInterval. The admin is told either way. Other
+// errors are returned as they are.
+func healFeed(source *SourceConfig, since time.Time, fetchErr error, health *HealthTracker, notifier *AdminNotifier, config *Config) ([]fetcher.NewsItem, error) {
+	var statusErr *fetcher.StatusError
+	if !source.isFeed() || !errors.As(fetchErr, &statusErr) || !statusErr.Gone() {
+		return nil, fetchErr
+	}
+
+	relocation := health.Relocation(source.Name, source.URL)
+	if relocation != nil && relocation.To != "" {
+		items, err := fetchMovedFeed(source, relocation.To, since, config)
+		if err == nil {
+			return items, nil
+		}
+		LogError("Failed to read moved feed", err, "source", source.Name, "url", relocation.To)
+	}
+	if relocation != nil && time.Since(relocation.CheckedAt) < FeedRediscoveryInterval {
+		return nil, fetchErr
+	}
+
+	feedURL, err := url.Parse(source.URL)
+	if err != nil {
+		return nil, fetchErr
+	}
+	siteURL := feedURL.Scheme + "://" + feedURL.Host + "/"
+	LogInfo("Feed is gone, looking for its new address", "source", source.Name, "site", siteURL)
+	candidates, err := fetcher.DiscoverFeeds(siteURL, source.MaxBodySize, source.HTTP)
+	if err != nil {
+		LogError("Failed to discover feeds", err, "source", source.Name)
+		return nil, fetchErr
+	}
+
+	for _, candidate := range candidates {
+		if candidate.Err != nil || candidate.URL == source.URL || candidate.DatedItems == 0 {
+			continue
+		}
+		// All of the candidate's items are fetched to compare them with those seen from the source
+		items, err := fetchMovedFeed(source, candidate.URL, time.Time{}, config)
+		if err != nil {
+			continue
+		}
+		if !health.RecognizesFeed(source.Name, candidate.Title, items) {
+			LogInfo("Skipping unrelated feed", "source", source.Name, "url", candidate.URL)
+			continue
+		}
+		LogInfo("Reading moved feed", "source", source.Name, "url", candidate.URL)
+		health.SetRelocation(source.Name, &FeedRelocation{From: source.URL, To: candidate.URL, CheckedAt: time.Now()})
+		notifier.Error(source.Name, locatingFeedOperation,
+			fmt.Errorf("%s is gone, reading %s found on the site until the source's URL is updated", source.URL, candidate.URL))
+		var newItems []fetcher.NewsItem
+		for _, item := range items {
+			if item.PublishedOn.After(since) {
+				newItems = append(newItems, item)
+			}
+		}
+		return newItems, nil
+	}
+
+	health.SetRelocation(source.Name, &FeedRelocation{From: source.URL, CheckedAt: time.Now()})
+	notifier.Error(source.Name, locatingFeedOperation,
+		fmt.Errorf("%s is gone and no feed found on %s is the source's; update the source's URL", source.URL, siteURL))
+	return nil, fetchErr
+}
+
+// fetchMovedFeed fetches the items of the source's feed from feedURL instead of its configured URL.
+func fetchMovedFeed(source *SourceConfig, feedURL string, since time.Time, config *Config) ([]fetcher.NewsItem, error) {
+	moved := *source
+	moved.URL = feedURL
+	feedFetcher, err := newFetcher(&moved)
+	if err != nil {
+		return nil, err
+	}
+	return feedFetcher.Fetch(since, config.RetryAttempts, config.RetryDelay)
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONFile persists a single value of type T as a JSON document on disk.
+type JSONFile[T any] struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewJSONFile creates a JSONFile backed by the given path.
+func NewJSONFile[T any](path string) *JSONFile[T] {
+	return &JSONFile[T]{path: path}
+}
+
+// Load reads the stored value. A missing or empty file yields the zero value.
+func (f *JSONFile[T]) Load() (T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.load()
+}
+
+// Save writes the value to disk, replacing the previous contents atomically.
+func (f *JSONFile[T]) Save(value T) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.save(value)
+}
+
+// Update loads the stored value, applies fn to it and saves the result.
+// Nothing is written if fn returns an error.
+func (f *JSONFile[T]) Update(fn func(*T) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, err := f.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(&value); err != nil {
+		return err
+	}
+	return f.save(value)
+}
+
+func (f *JSONFile[T]) load() (T, error) {
+	var value T
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return value, nil
+	}
+	if err != nil {
+		return value, fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return value, nil
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode %s: %w", f.path, err)
+	}
+	return value, nil
+}
+
+func (f *JSONFile[T]) save(value T) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", f.path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated document.
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", f.path, err)
+	}
+	return nil
+}
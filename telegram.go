@@ -3,8 +3,8 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"time"
@@ -12,124 +12,102 @@ import (
 
 // TelegramService handles sending messages to a Telegram bot.
 type TelegramService struct {
-	apiKey         string
-	targetChannels map[string]string
+	apiKey string
 }
 
 // NewTelegramService creates a new TelegramService.
-func NewTelegramService(apiKey string, targetChannels map[string]string) *TelegramService {
+func NewTelegramService(apiKey string) *TelegramService {
 	return &TelegramService{
-		apiKey:         apiKey,
-		targetChannels: targetChannels,
+		apiKey: apiKey,
 	}
 }
 
-// SendMessage sends a message to the specified Telegram chat.
-func (s *TelegramService) SendMessage(chatID, sourceName, message string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", s.apiKey)
+// truncateCaption shortens a photo caption to Telegram's caption limit.
+func truncateCaption(caption string) string {
+	runes := []rune(caption)
+	if len(runes) > MaxTelegramCaptionLength {
+		return string(runes[:MaxTelegramCaptionLength-3]) + "..."
+	}
+	return caption
+}
 
-	messageToSend := message
+// SendOptions holds optional delivery parameters for channel posts.
+type SendOptions struct {
+	ReplyToMessageID    int
+	DisableNotification bool
+	ReplyMarkup         *InlineKeyboardMarkup
+}
 
-	// Check if the chatID is a target channel and append the identifier if so
-	isTargetChannel := false
-	for _, channel := range s.targetChannels {
-		if channel == chatID {
-			isTargetChannel = true
-			break
+// apply adds the options to a sendMessage or sendPhoto payload.
+func (o SendOptions) apply(payload map[string]any) {
+	if o.ReplyToMessageID != 0 {
+		payload["reply_parameters"] = map[string]any{
+			"message_id":                  o.ReplyToMessageID,
+			"allow_sending_without_reply": true,
 		}
 	}
-
-	if isTargetChannel {
-		if identifier, ok := s.targetChannels[sourceName]; ok {
-			messageToSend = message + fmt.Sprintf("\n\n%s", identifier)
-		}
+	if o.DisableNotification {
+		payload["disable_notification"] = true
 	}
+	if o.ReplyMarkup != nil {
+		payload["reply_markup"] = o.ReplyMarkup
+	}
+}
+
+// SendMessage sends a message to the specified Telegram chat and returns the sent message.
+func (s *TelegramService) SendMessage(chatID, message string) (*Message, error) {
+	return s.SendMessageWithOptions(chatID, message, SendOptions{})
+}
 
-	requestBody, err := json.Marshal(map[string]string{
+// SendMessageWithOptions sends a message with the given delivery options and returns the sent message.
+func (s *TelegramService) SendMessageWithOptions(chatID, message string, opts SendOptions) (*Message, error) {
+	payload := map[string]any{
 		"chat_id":    chatID,
-		"text":       messageToSend,
+		"text":       message,
 		"parse_mode": "HTML",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
 	}
+	opts.apply(payload)
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Telegram API response: %s", string(body))
-		return fmt.Errorf("failed to send message with status code: %d", resp.StatusCode)
+	var msg Message
+	if err := s.callAPI("sendMessage", payload, &msg); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
 	}
 
 	log.Println("Message sent to Telegram successfully.")
-	return nil
+	return &msg, nil
 }
 
-// SendPhoto sends a photo with a caption to the specified Telegram chat.
-func (s *TelegramService) SendPhoto(chatID, photoURL, sourceName, caption string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendPhoto", s.apiKey)
-
-	fullCaption := caption
-
-	// Check if the chatID is a target channel and append the identifier if so
-	isTargetChannel := false
-	for _, channel := range s.targetChannels {
-		if channel == chatID {
-			isTargetChannel = true
-			break
-		}
-	}
+// SendPhoto sends a photo with a caption to the specified Telegram chat and returns the sent message.
+// If the photo cannot be delivered, the caption is sent as a text message with the image link instead.
+func (s *TelegramService) SendPhoto(chatID, photoURL, caption string) (*Message, error) {
+	return s.SendPhotoWithOptions(chatID, photoURL, caption, SendOptions{})
+}
 
-	if isTargetChannel {
-		if identifier, ok := s.targetChannels[sourceName]; ok {
-			fullCaption = caption + fmt.Sprintf("\n\n%s", identifier)
-		}
-	}
-	// log.Printf("Caption sent to telegram: %s", fullCaption)
-	runes := []rune(fullCaption)
-	if len(runes) > MaxTelegramCaptionLength {
-		fullCaption = string(runes[:MaxTelegramCaptionLength-3]) + "..."
-	}
-	// Prepare the request body as JSON
-	requestBody, err := json.Marshal(map[string]string{
+// SendPhotoWithOptions sends a photo with the given delivery options and returns the sent message.
+func (s *TelegramService) SendPhotoWithOptions(chatID, photoURL, caption string, opts SendOptions) (*Message, error) {
+	fullCaption := truncateCaption(caption)
+	payload := map[string]any{
 		"chat_id":    chatID,
 		"photo":      photoURL,
 		"caption":    fullCaption,
 		"parse_mode": "HTML",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON for sendPhoto: %w", err)
 	}
+	opts.apply(payload)
 
 	var lastErr error
 	for i := 0; i < MaxPhotoRetries; i++ {
-		resp, err := http.Post(url, "application/json", bytes.NewBuffer(requestBody))
-		if err != nil {
-			lastErr = fmt.Errorf("failed to send photo by URL: %w", err)
-			LogError("Failed to send photo", lastErr, "attempt", i+1)
-			if i < MaxPhotoRetries-1 {
-				time.Sleep(PhotoRetryDelay)
-			}
-			continue
-		}
-
-		if resp.StatusCode == http.StatusOK {
-			resp.Body.Close()
+		var msg Message
+		err := s.callAPI("sendPhoto", payload, &msg)
+		if err == nil {
 			LogInfo("Photo sent successfully by URL", "chat_id", chatID)
-			return nil
+			return &msg, nil
 		}
 
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		lastErr = fmt.Errorf("telegram API error: %s", string(body))
-		LogError("Telegram API error while sending photo", lastErr, "status_code", resp.StatusCode, "response", string(body), "attempt", i+1)
+		lastErr = fmt.Errorf("failed to send photo by URL: %w", err)
+		LogError("Failed to send photo", lastErr, "attempt", i+1)
 
-		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode >= 400 && apiErr.ErrorCode < 500 && apiErr.ErrorCode != http.StatusTooManyRequests {
 			break
 		}
 
@@ -141,5 +119,199 @@ func (s *TelegramService) SendPhoto(chatID, photoURL, sourceName, caption string
 	LogError("All retries for sending photo failed, falling back to text message", lastErr, "chat_id", chatID)
 
 	fallbackMessage := fmt.Sprintf("%s\n\n(Image: %s)", caption, photoURL)
-	return s.SendMessage(chatID, sourceName, fallbackMessage)
+	return s.SendMessageWithOptions(chatID, fallbackMessage, opts)
+}
+
+// EditPost replaces the text of a published post, using the caption for photo posts. Telegram
+// removes the inline keyboard of an edited message unless it is sent again, so markup is the
+// keyboard the post keeps, if any.
+func (s *TelegramService) EditPost(chatID string, messageID int, text string, isPhoto bool, markup *InlineKeyboardMarkup) error {
+	if !isPhoto {
+		return s.EditMessageText(chatID, messageID, text, markup)
+	}
+
+	payload := map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"caption":    truncateCaption(text),
+		"parse_mode": "HTML",
+	}
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+	return s.callAPI("editMessageCaption", payload, nil)
+}
+
+// DeleteMessage deletes a message from the specified chat.
+func (s *TelegramService) DeleteMessage(chatID string, messageID int) error {
+	payload := map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+	}
+	return s.callAPI("deleteMessage", payload, nil)
+}
+
+// InlineKeyboardButton is a single button of an inline keyboard.
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+// InlineKeyboardMarkup is an inline keyboard attached to a message.
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// Chat is the subset of a Telegram chat object used by the bot.
+type Chat struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// User is the subset of a Telegram user object used by the bot.
+type User struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+// PhotoSize is one size variant of a photo attached to a message.
+type PhotoSize struct {
+	FileID string `json:"file_id"`
+}
+
+// Message is the subset of a Telegram message object used by the bot.
+type Message struct {
+	MessageID      int         `json:"message_id"`
+	Chat           Chat        `json:"chat"`
+	From           *User       `json:"from,omitempty"`
+	Text           string      `json:"text,omitempty"`
+	Caption        string      `json:"caption,omitempty"`
+	Photo          []PhotoSize `json:"photo,omitempty"`
+	ReplyToMessage *Message    `json:"reply_to_message,omitempty"`
+}
+
+// CallbackQuery is sent when a user presses an inline keyboard button.
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// Update is a single incoming update returned by getUpdates.
+type Update struct {
+	UpdateID      int            `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+// apiResponse is the envelope of every Telegram Bot API response.
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	ErrorCode   int             `json:"error_code,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+}
+
+// APIError is an error reported by the Telegram Bot API.
+type APIError struct {
+	Method      string
+	ErrorCode   int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram API error in %s (%d): %s", e.Method, e.ErrorCode, e.Description)
+}
+
+// callAPI invokes a Telegram Bot API method and decodes its result into result, if non-nil.
+func (s *TelegramService) callAPI(method string, payload any, result any) error {
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/%s", s.apiKey, method)
+
+	requestBody, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	client := &http.Client{Timeout: DefaultHTTPTimeout + time.Duration(UpdatesPollTimeout)*time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("failed to decode %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+	if !apiResp.OK {
+		log.Printf("Telegram API response: %s", apiResp.Description)
+		errorCode := apiResp.ErrorCode
+		if errorCode == 0 {
+			errorCode = resp.StatusCode
+		}
+		return &APIError{Method: method, ErrorCode: errorCode, Description: apiResp.Description}
+	}
+	if result != nil && len(apiResp.Result) > 0 {
+		if err := json.Unmarshal(apiResp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// SendForceReply sends a message that asks the recipient to reply to it.
+func (s *TelegramService) SendForceReply(chatID, text string) (*Message, error) {
+	payload := map[string]any{
+		"chat_id":      chatID,
+		"text":         text,
+		"parse_mode":   "HTML",
+		"reply_markup": map[string]bool{"force_reply": true},
+	}
+
+	var msg Message
+	if err := s.callAPI("sendMessage", payload, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// EditMessageText replaces the text and inline keyboard of a previously sent message.
+func (s *TelegramService) EditMessageText(chatID string, messageID int, text string, markup *InlineKeyboardMarkup) error {
+	payload := map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"text":       text,
+		"parse_mode": "HTML",
+	}
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+	return s.callAPI("editMessageText", payload, nil)
+}
+
+// GetUpdates fetches incoming updates starting at offset, long-polling for up to timeout seconds.
+func (s *TelegramService) GetUpdates(offset, timeout int) ([]Update, error) {
+	payload := map[string]any{
+		"offset":          offset,
+		"timeout":         timeout,
+		"allowed_updates": []string{"message", "callback_query"},
+	}
+
+	var updates []Update
+	if err := s.callAPI("getUpdates", payload, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// AnswerCallbackQuery acknowledges an inline button press, optionally showing a short notice.
+func (s *TelegramService) AnswerCallbackQuery(callbackQueryID, text string) error {
+	payload := map[string]any{
+		"callback_query_id": callbackQueryID,
+		"text":              text,
+	}
+	return s.callAPI("answerCallbackQuery", payload, nil)
 }